@@ -0,0 +1,254 @@
+package ghb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// batchRequest is a sub-request of a batch, its body is the JSON request
+// body of the route.
+type batchRequest struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Headers map[string][]string `json:"headers"`
+	Body    json.RawMessage     `json:"body"`
+}
+
+// batchResponse is the response of a sub-request, the body is embedded as is
+// when it is JSON and as a string otherwise.
+type batchResponse struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    any                 `json:"body,omitempty"`
+}
+
+// batchHeaders are the headers of a batch request that do not apply to its
+// sub-requests.
+var batchHeaders = []string{
+	"Accept-Encoding",
+	"Connection",
+	"Content-Encoding",
+	"Content-Length",
+	"Content-Type",
+	"Upgrade",
+}
+
+// serveBatch serves a JSON array of sub-requests with the registered routes
+// and responds with the array of their responses, in the same order. The
+// sub-requests carry the headers of the batch request, so they are
+// authenticated like it, and go through the same interceptors as individual
+// requests.
+func (s *Server) serveBatch(w http.ResponseWriter, r *http.Request) {
+	if !s.limitBody(w, r, nil) {
+		return
+	}
+	var requests []batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+		if errors.Is(err, errBodyTooLarge) {
+			writeError(w, err)
+			return
+		}
+		badRequestf(w, "invalid batch request: %v", err)
+		return
+	}
+	if s.maxBatchRequests > 0 && len(requests) > s.maxBatchRequests {
+		badRequestf(w, "batch of %d requests exceeds the maximum of %d", len(requests), s.maxBatchRequests)
+		return
+	}
+	responses := make([]batchResponse, len(requests))
+	budget := &batchBudget{max: s.maxBatchTotal}
+	concurrency := s.batchConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if budget.exceeded.Load() {
+				// the responses would be dropped anyway.
+				responses[i] = statusResponse(budget.status())
+				return
+			}
+			responses[i] = s.callBatch(r, req, budget)
+		}()
+	}
+	wg.Wait()
+
+	data, err := json.Marshal(responses)
+	if err != nil {
+		internalServerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.Write(data)
+}
+
+func (s *Server) callBatch(parent *http.Request, req batchRequest, budget *batchBudget) batchResponse {
+	rec := &batchResponseWriter{header: http.Header{}, max: s.maxBatchResponse, budget: budget}
+	sub, err := s.newBatchRequest(parent, req)
+	if err != nil {
+		badRequest(rec, err)
+	} else {
+		s.mux.ServeHTTP(rec, sub)
+	}
+	return rec.response()
+}
+
+func (s *Server) newBatchRequest(parent *http.Request, req batchRequest) (*http.Request, error) {
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	if !strings.HasPrefix(req.Path, "/") {
+		return nil, fmt.Errorf("invalid path %q", req.Path)
+	}
+	var body io.Reader = http.NoBody
+	if req.Body != nil {
+		body = bytes.NewReader(req.Body)
+	}
+	sub, err := http.NewRequestWithContext(parent.Context(), strings.ToUpper(req.Method), req.Path, body)
+	if err != nil {
+		return nil, err
+	}
+	if sub.URL.Path == path.Join("/", s.batchPath) {
+		return nil, fmt.Errorf("nested batch request %q", req.Path)
+	}
+	sub.Host = parent.Host
+	sub.RemoteAddr = parent.RemoteAddr
+	sub.Header = parent.Header.Clone()
+	for _, key := range batchHeaders {
+		sub.Header.Del(key)
+	}
+	if req.Body != nil {
+		sub.Header.Set("Content-Type", contentTypeJSON)
+	}
+	for key, values := range req.Headers {
+		sub.Header[http.CanonicalHeaderKey(key)] = values
+	}
+	return sub, nil
+}
+
+// errBatchResponseTooLarge fails the writes of a sub-request whose response
+// outgrows the maximum size.
+var errBatchResponseTooLarge = errors.New("batch response too large")
+
+// batchBudget bounds the bytes buffered for the responses of all the
+// sub-requests of a batch, once exceeded the sub-requests still running and
+// the remaining ones fail. Zero max means unlimited.
+type batchBudget struct {
+	max      int64
+	used     atomic.Int64
+	exceeded atomic.Bool
+}
+
+// take reserves n bytes, it reports false once the budget is exceeded.
+func (b *batchBudget) take(n int) bool {
+	if b.max <= 0 {
+		return true
+	}
+	if b.exceeded.Load() || b.used.Add(int64(n)) > b.max {
+		b.exceeded.Store(true)
+		return false
+	}
+	return true
+}
+
+func (b *batchBudget) status() *status.Status {
+	return status.Newf(codes.ResourceExhausted, "batch responses exceed the maximum size of %d bytes", b.max)
+}
+
+// batchResponseWriter buffers the response of a sub-request, up to max bytes
+// of body unless max is zero, and within the budget of the batch.
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	max    int64
+	budget *batchBudget
+	// failed replaces the response once it is too large.
+	failed *status.Status
+}
+
+func (w *batchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *batchResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.failed != nil {
+		return 0, errBatchResponseTooLarge
+	}
+	if w.max > 0 && int64(w.body.Len()+len(p)) > w.max {
+		w.fail(status.Newf(codes.ResourceExhausted, "response exceeds the maximum size of %d bytes", w.max))
+		return 0, errBatchResponseTooLarge
+	}
+	if w.budget != nil && !w.budget.take(len(p)) {
+		w.fail(w.budget.status())
+		return 0, errBatchResponseTooLarge
+	}
+	return w.body.Write(p)
+}
+
+func (w *batchResponseWriter) fail(st *status.Status) {
+	w.failed = st
+	w.body = bytes.Buffer{}
+}
+
+// Flush lets streaming routes write their responses, which are buffered
+// whole.
+func (w *batchResponseWriter) Flush() {}
+
+func (w *batchResponseWriter) response() batchResponse {
+	if w.failed != nil {
+		return statusResponse(w.failed)
+	}
+	res := batchResponse{Status: w.status}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	w.header.Del("Content-Length")
+	if len(w.header) > 0 {
+		res.Headers = w.header
+	}
+	if w.body.Len() == 0 {
+		return res
+	}
+	mediaType, _, _ := mime.ParseMediaType(w.header.Get("Content-Type"))
+	if mediaType == contentTypeJSON && json.Valid(w.body.Bytes()) {
+		res.Body = json.RawMessage(w.body.Bytes())
+	} else {
+		res.Body = w.body.String()
+	}
+	return res
+}
+
+// statusResponse is the response of a sub-request failing with st.
+func statusResponse(st *status.Status) batchResponse {
+	rec := &batchResponseWriter{header: http.Header{}}
+	writeStatus(rec, http.StatusInternalServerError, st)
+	return rec.response()
+}
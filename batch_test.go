@@ -0,0 +1,142 @@
+package ghb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func postBatch(t *testing.T, addr, body string, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/batch", strings.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, string(data)
+}
+
+func TestServer_batch(t *testing.T) {
+	auth := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if token := md.Get("authorization"); len(token) == 0 || token[0] != "Bearer secret" {
+			return nil, status.Error(codes.Unauthenticated, "missing token")
+		}
+		return handler(ctx, req)
+	}
+	s := NewServer(WithBatch("/batch", 2), WithUnaryInterceptors(auth))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	body := `[
+		{"method": "GET", "path": "/v1/users/1"},
+		{"method": "POST", "path": "/v1/users", "body": {"id": "2", "name": "Jane"}},
+		{"method": "DELETE", "path": "/v1/users/3"},
+		{"method": "GET", "path": "/v1/users/missing"},
+		{"method": "GET", "path": "/v1/exports/csv"},
+		{"method": "GET", "path": "/v1/unknown"},
+		{"method": "POST", "path": "/batch", "body": []}
+	]`
+	res, data := postBatch(t, addr, body, http.Header{"Authorization": {"Bearer secret"}})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, contentTypeJSON, res.Header.Get("Content-Type"))
+	require.JSONEq(t, `[
+		{"status": 200, "headers": {"Content-Type": ["application/json"], "X-User-Source": ["directory"]}, "body": {"id": "1", "name": "John Doe", "age": 30}},
+		{"status": 200, "headers": {"Content-Type": ["application/json"]}, "body": {"id": "2", "name": "Jane", "age": 0}},
+		{"status": 204},
+		{"status": 404, "headers": {"Content-Type": ["application/json"], "X-Content-Type-Options": ["nosniff"]}, "body": {"code": 5, "message": "user not found", "details": []}},
+		{"status": 200, "headers": {"Content-Type": ["text/csv"]}, "body": "id,name\n1,Jane\n"},
+		{"status": 404, "headers": {"Content-Type": ["application/json"], "X-Content-Type-Options": ["nosniff"]}, "body": {"code": 5, "message": "no route for GET /v1/unknown", "details": []}},
+		{"status": 400, "headers": {"Content-Type": ["application/json"], "X-Content-Type-Options": ["nosniff"]}, "body": {"code": 3, "message": "nested batch request \"/batch\"", "details": []}}
+	]`, data)
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		_, data := postBatch(t, addr, `[{"path": "/v1/users/1"}]`, nil)
+		require.JSONEq(t, `[
+			{"status": 401, "headers": {"Content-Type": ["application/json"], "X-Content-Type-Options": ["nosniff"]}, "body": {"code": 16, "message": "missing token", "details": []}}
+		]`, data)
+	})
+
+	t.Run("Headers", func(t *testing.T) {
+		_, data := postBatch(t, addr, `[{"path": "/v1/users/1", "headers": {"authorization": ["Bearer secret"], "X-Tags": ["a", "b"]}}]`, nil)
+		require.JSONEq(t, `[
+			{"status": 200, "headers": {"Content-Type": ["application/json"], "X-User-Source": ["directory"]}, "body": {"id": "1", "name": "John Doe", "age": 30}}
+		]`, data)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		res, _ := postBatch(t, addr, `{"path": "/v1/users/1"}`, nil)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestServer_batchConcurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	slow := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return handler(ctx, req)
+	}
+	s := NewServer(WithBatch("/batch", 2), WithUnaryInterceptors(slow))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	body := `[{"path": "/v1/users/1"}, {"path": "/v1/users/2"}, {"path": "/v1/users/3"}, {"path": "/v1/users/4"}]`
+	res, data := postBatch(t, addr, body, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, 4, strings.Count(data, `"status":200`))
+	require.Equal(t, int32(2), maxRunning.Load())
+}
+
+func TestServer_batchLimits(t *testing.T) {
+	s := NewServer(WithBatch("/batch", 1), WithBatchLimits(2, 20, 0))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	res, _ := postBatch(t, addr, `[{"path": "/v1/users/1"}, {"path": "/v1/users/2"}, {"path": "/v1/users/3"}]`, nil)
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res, data := postBatch(t, addr, `[{"path": "/v1/exports/csv"}, {"path": "/v1/downloads/csv"}]`, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.JSONEq(t, `[
+		{"status": 200, "headers": {"Content-Type": ["text/csv"]}, "body": "id,name\n1,Jane\n"},
+		{"status": 500, "headers": {"Content-Type": ["application/json"], "X-Content-Type-Options": ["nosniff"]}, "body": {"code": 8, "message": "response exceeds the maximum size of 20 bytes", "details": []}}
+	]`, data)
+
+	// the requests after the one exceeding the total are not run.
+	s = NewServer(WithBatch("/batch", 1), WithBatchLimits(0, 0, 20))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr = serveTest(t, s)
+	res, data = postBatch(t, addr, `[{"path": "/v1/exports/csv"}, {"path": "/v1/exports/csv"}, {"method": "DELETE", "path": "/v1/users/1"}]`, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	exceeded := `{"status": 500, "headers": {"Content-Type": ["application/json"], "X-Content-Type-Options": ["nosniff"]}, "body": {"code": 8, "message": "batch responses exceed the maximum size of 20 bytes", "details": []}}`
+	require.JSONEq(t, `[
+		{"status": 200, "headers": {"Content-Type": ["text/csv"]}, "body": "id,name\n1,Jane\n"},
+		`+exceeded+`,
+		`+exceeded+`
+	]`, data)
+}
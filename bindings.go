@@ -0,0 +1,89 @@
+package ghb
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/malayanand/ghb/api"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// fieldBinding binds a field of a request message to a header or a cookie,
+// the header takes precedence if the request has both.
+type fieldBinding struct {
+	key    string
+	header string
+	cookie string
+}
+
+// fieldBindings returns the header and cookie bindings of the fields of md.
+func fieldBindings(md protoreflect.MessageDescriptor) []fieldBinding {
+	var bindings []fieldBinding
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		rule := proto.GetExtension(fd.Options(), api.E_Field).(*api.FieldRule)
+		if rule.GetHeader() == "" && rule.GetCookie() == "" {
+			continue
+		}
+		bindings = append(bindings, fieldBinding{key: jsonKey(fd), header: rule.GetHeader(), cookie: rule.GetCookie()})
+	}
+	return bindings
+}
+
+// bindRequest returns the values of the headers and cookies of the request
+// bound to fields, and adds them to the path params. Fields bound to a path
+// param as well are a conflict.
+func bindRequest(r *http.Request, bindings []fieldBinding, params map[string]string) (map[string]string, error) {
+	if len(bindings) == 0 {
+		return nil, nil
+	}
+	bound := make(map[string]string, len(bindings))
+	for _, b := range bindings {
+		var value string
+		if b.header != "" {
+			value = r.Header.Get(b.header)
+		}
+		if value == "" && b.cookie != "" {
+			if cookie, err := r.Cookie(b.cookie); err == nil {
+				value = cookie.Value
+			}
+		}
+		if value == "" {
+			continue
+		}
+		if existing, ok := params[b.key]; ok {
+			return nil, fmt.Errorf("parameter conflict: %q already exists with value %v", b.key, existing)
+		}
+		bound[b.key] = value
+	}
+	for k, v := range bound {
+		params[k] = v
+	}
+	return bound, nil
+}
+
+// checkBindings reports fields bound to a header or cookie whose value was
+// overridden by the body of the request.
+func checkBindings(msg proto.Message, bound map[string]string) error {
+	if len(bound) == 0 {
+		return nil
+	}
+	expected := msg.ProtoReflect().New()
+	if err := unmarshalBytes(nil, expected.Interface(), bound); err != nil {
+		return err
+	}
+	keys, err := jsonToProtoKeys(msg)
+	if err != nil {
+		return err
+	}
+	actual := msg.ProtoReflect()
+	for key := range bound {
+		fd := actual.Descriptor().Fields().ByName(protoreflect.Name(keys[key]))
+		if !actual.Get(fd).Equal(expected.Get(fd)) {
+			return fmt.Errorf("parameter conflict: %q is set by the body and a header or cookie", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package ghb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_bindRequest(t *testing.T) {
+	bindings := fieldBindings((&test.GetUserRequest{}).ProtoReflect().Descriptor())
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		params   map[string]string
+		body     []byte
+		expected *test.GetUserRequest
+		isErr    bool
+	}{
+		{
+			name:     "header and cookie",
+			header:   http.Header{"X-Tenant-Id": {"acme"}, "Cookie": {"session=s1"}},
+			params:   map[string]string{"id": "123"},
+			expected: &test.GetUserRequest{Id: "123", Tenant: "acme", Session: "s1"},
+		},
+		{
+			name:     "body agrees with the header",
+			header:   http.Header{"X-Tenant-Id": {"acme"}},
+			params:   map[string]string{},
+			body:     []byte(`{"id": "123", "tenant": "acme"}`),
+			expected: &test.GetUserRequest{Id: "123", Tenant: "acme"},
+		},
+		{
+			name:   "conflict with a path param",
+			header: http.Header{"X-Tenant-Id": {"acme"}},
+			params: map[string]string{"tenant": "other"},
+			isErr:  true,
+		},
+		{
+			name:   "conflict with the body",
+			header: http.Header{"X-Tenant-Id": {"acme"}},
+			params: map[string]string{},
+			body:   []byte(`{"tenant": "other"}`),
+			isErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header = tt.header
+			bound, err := bindRequest(r, bindings, tt.params)
+			if err == nil {
+				actual := &test.GetUserRequest{}
+				require.NoError(t, unmarshalBytes(tt.body, actual, tt.params))
+				err = checkBindings(actual, bound)
+				if err == nil {
+					require.EqualExportedValues(t, tt.expected, actual)
+				}
+			}
+			if tt.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestServer_bindings(t *testing.T) {
+	addr := newTestServer(t)
+
+	get := func(t *testing.T, header http.Header) int {
+		req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/v1/users/123", nil)
+		require.NoError(t, err)
+		req.Header = header
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, get(t, http.Header{"X-Tenant-Id": {"acme"}}))
+	require.Equal(t, http.StatusForbidden, get(t, http.Header{"X-Tenant-Id": {"blocked"}}))
+	require.Equal(t, http.StatusUnauthorized, get(t, http.Header{"Cookie": {"session=expired"}}))
+}
@@ -0,0 +1,297 @@
+package ghb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Client calls the methods of services served by a ghb Server over HTTP,
+// following the http rules of the methods. It is used by the clients
+// generated by protoc-gen-ghb.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the http client used to send requests, defaults to
+// http.DefaultClient.
+func WithHTTPClient(c *http.Client) ClientOption {
+	return func(client *Client) {
+		client.httpClient = c
+	}
+}
+
+// NewClient returns a client for the server at baseURL, e.g.
+// "https://api.example.com".
+func NewClient(baseURL string, opts ...ClientOption) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Call sends req with the method to the path template of an http rule and
+// decodes the response into res. The variables of the template, and the
+// fields bound to headers and cookies, are filled from req. The remaining
+// fields are sent in the body, or in the query for methods without a body.
+// The outgoing metadata of ctx is sent as headers. Errors are returned as
+// grpc statuses.
+func (c *Client) Call(ctx context.Context, method, pathTemplate string, req, res proto.Message) error {
+	_, err := c.call(ctx, method, pathTemplate, req, res)
+	return err
+}
+
+// call is Call returning the headers of the response, which are nil if no
+// response was received.
+func (c *Client) call(ctx context.Context, method, pathTemplate string, req, res proto.Message) (http.Header, error) {
+	r, err := c.newRequest(ctx, method, pathTemplate, req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode request: %v", err)
+	}
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.Header, status.Errorf(codes.Unavailable, "failed to read response: %v", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.Header, decodeStatus(resp.StatusCode, body).Err()
+	}
+	if err := decodeResponse(resp.Header, body, res); err != nil {
+		return resp.Header, status.Errorf(codes.Internal, "failed to decode response: %v", err)
+	}
+	return resp.Header, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, pathTemplate string, msg proto.Message) (*http.Request, error) {
+	m := msg.ProtoReflect()
+	used := make(map[string]bool)
+	path, err := expandPath(pathTemplate, m, used)
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header)
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		header = headerFromMetadata(md)
+	}
+	if err := setRequestBindings(header, m, used); err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	switch {
+	case !hasRequestBody(method):
+		query, err := queryValues(m, used)
+		if err != nil {
+			return nil, err
+		}
+		if len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+	case hasHttpBody(m.Descriptor()):
+		bodyMsg := m
+		if fd := httpBodyField(m.Descriptor()); fd != nil {
+			bodyMsg = m.Get(fd).Message()
+		}
+		contentType, data := getHttpBody(bodyMsg)
+		if contentType == "" {
+			contentType = contentTypeOctetStream
+		}
+		header.Set("Content-Type", contentType)
+		body = bytes.NewReader(data)
+	default:
+		value, err := marshalMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		if fields, ok := value.(map[string]any); ok {
+			for key := range used {
+				delete(fields, key)
+			}
+		}
+		data, err := jsonCodec{}.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		header.Set("Content-Type", contentTypeJSON)
+		body = bytes.NewReader(data)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Accept", contentTypeJSON)
+	return r, nil
+}
+
+// expandPath fills the variables of the path template of a rule with the
+// values of the fields they name, the keys of the variables are added to
+// used.
+func expandPath(template string, msg protoreflect.Message, used map[string]bool) (string, error) {
+	var b strings.Builder
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			b.WriteString(rest)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable in path %q", template)
+		}
+		key := strings.TrimSuffix(rest[start+1:start+end], "...")
+		value, err := fieldString(msg, strings.Split(key, "."))
+		if err != nil {
+			return "", fmt.Errorf("path %q: %v", template, err)
+		}
+		used[key] = true
+		b.WriteString(rest[:start])
+		b.WriteString(url.PathEscape(value))
+		rest = rest[start+end+1:]
+	}
+}
+
+// hasRequestBody reports whether requests with the method carry the message
+// in their body rather than in the query.
+func hasRequestBody(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return false
+	}
+	return true
+}
+
+// setRequestBindings sets the headers and cookies bound to the fields of msg
+// which are set.
+func setRequestBindings(header http.Header, msg protoreflect.Message, used map[string]bool) error {
+	keys, err := descriptorKeys(msg.Descriptor())
+	if err != nil {
+		return err
+	}
+	for _, b := range fieldBindings(msg.Descriptor()) {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(keys[b.key]))
+		if used[b.key] || !msg.Has(fd) {
+			continue
+		}
+		value, err := fieldString(msg, []string{b.key})
+		if err != nil {
+			return err
+		}
+		if b.header != "" {
+			header.Set(b.header, value)
+		} else {
+			header.Add("Cookie", (&http.Cookie{Name: b.cookie, Value: value}).String())
+		}
+		used[b.key] = true
+	}
+	return nil
+}
+
+// queryValues returns the fields of msg which are set and not used yet as
+// query params, only fields of a single scalar value can be sent this way.
+func queryValues(msg protoreflect.Message, used map[string]bool) (url.Values, error) {
+	query := make(url.Values)
+	var err error
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		key := jsonKey(fd)
+		if used[key] {
+			return true
+		}
+		if fd.IsList() || fd.IsMap() || fd.Message() != nil {
+			err = fmt.Errorf("field %s can not be sent in the query", key)
+			return false
+		}
+		var value string
+		if value, err = fieldString(msg, []string{key}); err != nil {
+			return false
+		}
+		query.Set(key, value)
+		return true
+	})
+	return query, err
+}
+
+// decodeResponse decodes a successful response into msg, fields bound to
+// response headers are read from the headers unless the body has them. The
+// body of a response with a google.api.HttpBody field goes into that field.
+func decodeResponse(header http.Header, body []byte, msg proto.Message) error {
+	m := msg.ProtoReflect()
+	if isHttpBody(m.Descriptor()) {
+		setHttpBody(m, header.Get("Content-Type"), body)
+		return nil
+	}
+	params := make(map[string]string)
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if name := responseHeader(fd); name != "" && header.Get(name) != "" {
+			params[jsonKey(fd)] = header.Get(name)
+		}
+	}
+	if fd := httpBodyField(m.Descriptor()); fd != nil {
+		if err := unmarshalBytes(nil, msg, params); err != nil {
+			return err
+		}
+		setHttpBody(m.Mutable(fd).Message(), header.Get("Content-Type"), body)
+		return nil
+	}
+	if len(body) == 0 || isEmpty(m.Descriptor()) {
+		body = nil
+	}
+	return unmarshalBytes(body, msg, params)
+}
+
+// decodeStatus decodes the google.rpc.Status written by writeError, bodies of
+// any other format are turned into a status from the http status code.
+func decodeStatus(code int, body []byte) *status.Status {
+	var st struct {
+		Code    int32  `json:"code"`
+		Message string `json:"message"`
+		Details []struct {
+			TypeURL string `json:"type_url"`
+			Value   []byte `json:"value"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(body, &st); err != nil || st.Code == 0 {
+		message := strings.TrimSpace(string(body))
+		if message == "" {
+			message = http.StatusText(code)
+		}
+		return status.New(codeFromHTTPStatus(code), message)
+	}
+	p := &spb.Status{Code: st.Code, Message: st.Message}
+	for _, d := range st.Details {
+		p.Details = append(p.Details, &anypb.Any{TypeUrl: d.TypeURL, Value: d.Value})
+	}
+	return status.FromProto(p)
+}
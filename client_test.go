@@ -0,0 +1,111 @@
+package ghb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestClient_Call(t *testing.T) {
+	c := NewClient("http://" + newTestServer(t) + "/")
+	ctx := context.Background()
+
+	t.Run("path params", func(t *testing.T) {
+		res := &test.TestUser{}
+		require.NoError(t, c.Call(ctx, http.MethodGet, "/v1/users/{id}", &test.GetUserRequest{Id: "a b"}, res))
+		require.EqualExportedValues(t, &test.TestUser{Id: "a b", Name: "John Doe", Age: 30}, res)
+	})
+	t.Run("body", func(t *testing.T) {
+		req := &test.Profile{Id: "42", DisplayName: "Jane", Address: &test.Address{ZipCode: "10115"}, Labels: map[string]string{"env": "dev"}}
+		res := &test.Profile{}
+		require.NoError(t, c.Call(ctx, http.MethodPost, "/v1/profiles/{id}", req, res))
+		require.EqualExportedValues(t, req, res)
+	})
+	t.Run("bytes", func(t *testing.T) {
+		req := &test.Profile{Id: "42", Avatar: []byte{0xff, 0x00, 'p', 'n', 'g'}}
+		res := &test.Profile{}
+		require.NoError(t, c.Call(ctx, http.MethodPost, "/v1/profiles/{id}", req, res))
+		require.Equal(t, req.Avatar, res.Avatar)
+	})
+	t.Run("header and cookie bindings", func(t *testing.T) {
+		err := c.Call(ctx, http.MethodGet, "/v1/users/{id}", &test.GetUserRequest{Id: "1", Tenant: "blocked"}, &test.TestUser{})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		err = c.Call(ctx, http.MethodGet, "/v1/users/{id}", &test.GetUserRequest{Id: "1", Session: "expired"}, &test.TestUser{})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+	t.Run("empty response", func(t *testing.T) {
+		require.NoError(t, c.Call(ctx, http.MethodDelete, "/v1/users/{id}", &test.GetUserRequest{Id: "1"}, &emptypb.Empty{}))
+	})
+	t.Run("http bodies", func(t *testing.T) {
+		res := &httpbody.HttpBody{}
+		require.NoError(t, c.Call(ctx, http.MethodGet, "/v1/exports/{format}", &test.ExportRequest{Format: "csv"}, res))
+		require.Equal(t, "text/csv", res.ContentType)
+		require.Equal(t, "id,name\n1,Jane\n", string(res.Data))
+
+		req := &test.UploadAvatarRequest{Id: "7", Avatar: &httpbody.HttpBody{ContentType: "image/png", Data: []byte("png")}}
+		require.NoError(t, c.Call(ctx, http.MethodPost, "/v1/profiles/{id}/avatar", req, res))
+		require.Equal(t, "image/png", res.ContentType)
+		require.Equal(t, "7:png", string(res.Data))
+
+		rendering := &test.Rendering{}
+		require.NoError(t, c.Call(ctx, http.MethodGet, "/v1/renderings/{format}", &test.ExportRequest{Format: "html"}, rendering))
+		require.Equal(t, "html", rendering.Format)
+		require.Equal(t, "text/html", rendering.Body.ContentType)
+		require.Equal(t, "<p>Jane</p>", string(rendering.Body.Data))
+	})
+	t.Run("error", func(t *testing.T) {
+		err := c.Call(ctx, http.MethodGet, "/v1/users/{id}", &test.GetUserRequest{Id: "missing"}, &test.TestUser{})
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.NotFound, st.Code())
+		require.Equal(t, "user not found", st.Message())
+	})
+	t.Run("fields which can not be sent in the query", func(t *testing.T) {
+		err := c.Call(ctx, http.MethodGet, "/v1/profiles/{id}", &test.Profile{Id: "1", Tags: []string{"a"}}, &test.Profile{})
+		require.Equal(t, codes.Internal, status.Code(err))
+	})
+}
+
+func TestClient_queryParams(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	require.NoError(t, c.Call(context.Background(), http.MethodGet, "/v1/users/{id}", &test.TestUser{Id: "1", Name: "Jane", Age: 28}, &test.TestUser{}))
+	require.Equal(t, "age=28&name=Jane", query)
+}
+
+func Test_decodeStatus(t *testing.T) {
+	st := decodeStatus(http.StatusBadGateway, []byte("bad gateway"))
+	require.Equal(t, codes.Internal, st.Code())
+	require.Equal(t, "bad gateway", st.Message())
+
+	st = decodeStatus(http.StatusTooManyRequests, []byte(`{"code": 8, "message": "slow down", "details": [{"type_url": "type.googleapis.com/google.rpc.RetryInfo", "value": "CgIIAQ=="}]}`))
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Equal(t, "slow down", st.Message())
+	require.Len(t, st.Proto().Details, 1)
+	require.Equal(t, []byte{0x0a, 0x02, 0x08, 0x01}, st.Proto().Details[0].Value)
+}
+
+func Test_expandPath(t *testing.T) {
+	used := map[string]bool{}
+	path, err := expandPath("/v1/profiles/{id}/zips/{address.zipCode...}", (&test.Profile{Id: "a/b", Address: &test.Address{ZipCode: "10115"}}).ProtoReflect(), used)
+	require.NoError(t, err)
+	require.Equal(t, "/v1/profiles/a%2Fb/zips/10115", path)
+	require.Equal(t, map[string]bool{"id": true, "address.zipCode": true}, used)
+
+	_, err = expandPath("/v1/profiles/{id", (&test.Profile{}).ProtoReflect(), used)
+	require.Error(t, err)
+}
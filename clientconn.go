@@ -0,0 +1,78 @@
+package ghb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/malayanand/ghb/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// ClientConn is a grpc.ClientConnInterface calling the http rules of a ghb
+// server, so the clients generated by protoc-gen-go-grpc can be used over
+// plain HTTP. The http rules of the methods are looked up in the global
+// registry, only unary methods are supported.
+type ClientConn struct {
+	client *Client
+}
+
+var _ grpc.ClientConnInterface = (*ClientConn)(nil)
+
+// NewClientConn returns a connection to the server at baseURL.
+func NewClientConn(baseURL string, opts ...ClientOption) *ClientConn {
+	return &ClientConn{client: NewClient(baseURL, opts...)}
+}
+
+// Invoke calls the method, e.g. "/pkg.Service/Method", with the http rule of
+// the method. The grpc.Header call option receives the response headers.
+func (cc *ClientConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	httpRule, err := methodHttpRule(method)
+	if err != nil {
+		return err
+	}
+	req, ok := args.(proto.Message)
+	if !ok {
+		return status.Error(codes.Internal, errUnsupportedType(args).Error())
+	}
+	res, ok := reply.(proto.Message)
+	if !ok {
+		return status.Error(codes.Internal, errUnsupportedType(reply).Error())
+	}
+	header, err := cc.client.call(ctx, httpRule.Method.String(), httpRule.GetPath(), req, res)
+	for _, opt := range opts {
+		if o, ok := opt.(grpc.HeaderCallOption); ok && header != nil {
+			*o.HeaderAddr = metadataFromHeader(header)
+		}
+	}
+	return err
+}
+
+// NewStream fails, streaming methods are served over websockets which the
+// connection does not speak.
+func (cc *ClientConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Errorf(codes.Unimplemented, "streaming method %s is not supported over http", method)
+}
+
+// methodHttpRule returns the http rule of the method with the given full
+// method name.
+func methodHttpRule(method string) (*api.HttpRule, error) {
+	name := protoreflect.FullName(strings.Replace(strings.TrimPrefix(method, "/"), "/", ".", 1))
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+	if err != nil {
+		return nil, status.Errorf(codes.Unimplemented, "method %s not found", method)
+	}
+	md, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "%s is not a method", method)
+	}
+	httpRule, ok := proto.GetExtension(md.Options(), api.E_Http).(*api.HttpRule)
+	if !ok || httpRule == nil || httpRule.GetPath() == "" {
+		return nil, status.Errorf(codes.Unimplemented, "method %s has no http rule", method)
+	}
+	return httpRule, nil
+}
@@ -0,0 +1,49 @@
+package ghb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientConn(t *testing.T) {
+	client := test.NewTestServiceClient(NewClientConn("http://" + newTestServer(t)))
+	ctx := context.Background()
+
+	user, err := client.GetUser(ctx, &test.GetUserRequest{Id: "123"})
+	require.NoError(t, err)
+	require.EqualExportedValues(t, &test.TestUser{Id: "123", Name: "John Doe", Age: 30}, user)
+
+	var header metadata.MD
+	user, err = client.InviteUser(ctx, &test.TestUser{Id: "u1", Name: "Jane"}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, "Jane", user.Name)
+	require.Equal(t, []string{"/v1/users/u1"}, header.Get("location"))
+
+	_, err = client.GetUser(ctx, &test.GetUserRequest{Id: "missing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// the outgoing metadata is sent as headers.
+	_, err = client.GetUser(metadata.AppendToOutgoingContext(ctx, "x-tenant-id", "blocked"), &test.GetUserRequest{Id: "123"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Chat(ctx)
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func Test_methodHttpRule(t *testing.T) {
+	rule, err := methodHttpRule(test.TestService_GetUser_FullMethodName)
+	require.NoError(t, err)
+	require.Equal(t, "/v1/users/{id}", rule.GetPath())
+
+	_, err = methodHttpRule("/ghb.test.TestService/Unknown")
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = methodHttpRule("/ghb.test.TestUser/Id")
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
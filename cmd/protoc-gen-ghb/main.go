@@ -0,0 +1,136 @@
+// protoc-gen-ghb generates typed Go HTTP clients for the services of proto
+// files annotated with ghb.api.http rules. The clients call a ghb server
+// through a ghb.Client, for every unary method with an http rule.
+//
+// Usage:
+//
+//	protoc --ghb_out=. --ghb_opt=paths=source_relative service.proto
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/malayanand/ghb/api"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const version = "1.0.0"
+
+const (
+	contextPackage = protogen.GoImportPath("context")
+	ghbPackage     = protogen.GoImportPath("github.com/malayanand/ghb")
+)
+
+func main() {
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+	if *showVersion {
+		fmt.Printf("protoc-gen-ghb %v\n", version)
+		return
+	}
+
+	protogen.Options{}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		for _, f := range gen.Files {
+			if f.Generate {
+				generateFile(gen, f)
+			}
+		}
+		return nil
+	})
+}
+
+// httpMethod is a method of a service with an http rule.
+type httpMethod struct {
+	method *protogen.Method
+	rule   *api.HttpRule
+}
+
+// httpMethods returns the unary methods of the service with an http rule,
+// streaming methods are served over websockets which the client does not
+// speak.
+func httpMethods(service *protogen.Service) []httpMethod {
+	var methods []httpMethod
+	for _, method := range service.Methods {
+		if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+			continue
+		}
+		rule, ok := proto.GetExtension(method.Desc.Options(), api.E_Http).(*api.HttpRule)
+		if !ok || rule == nil || rule.GetPath() == "" {
+			continue
+		}
+		methods = append(methods, httpMethod{method: method, rule: rule})
+	}
+	return methods
+}
+
+func generateFile(gen *protogen.Plugin, file *protogen.File) {
+	var services []*protogen.Service
+	for _, service := range file.Services {
+		if len(httpMethods(service)) > 0 {
+			services = append(services, service)
+		}
+	}
+	if len(services) == 0 {
+		return
+	}
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_ghb.pb.go", file.GoImportPath)
+	g.P("// Code generated by protoc-gen-ghb. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P("// - protoc-gen-ghb v", version)
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+	for _, service := range services {
+		generateService(g, service)
+	}
+}
+
+func generateService(g *protogen.GeneratedFile, service *protogen.Service) {
+	clientName := service.GoName + "HTTPClient"
+	implName := unexport(clientName)
+	methods := httpMethods(service)
+
+	g.P("// ", clientName, " is the HTTP client API for ", service.GoName, " service.")
+	g.P("type ", clientName, " interface {")
+	for _, m := range methods {
+		g.Annotate(clientName+"."+m.method.GoName, m.method.Location)
+		g.P(m.method.Comments.Leading, methodSignature(g, m.method))
+	}
+	g.P("}")
+	g.P()
+	g.P("type ", implName, " struct {")
+	g.P("client *", g.QualifiedGoIdent(ghbPackage.Ident("Client")))
+	g.P("}")
+	g.P()
+	g.P("// New", clientName, " returns a client calling the http rules of ", service.GoName, " with c.")
+	g.P("func New", clientName, "(c *", g.QualifiedGoIdent(ghbPackage.Ident("Client")), ") ", clientName, " {")
+	g.P("return &", implName, "{client: c}")
+	g.P("}")
+	g.P()
+	for _, m := range methods {
+		g.P("func (c *", implName, ") ", methodSignature(g, m.method), " {")
+		g.P("out := new(", g.QualifiedGoIdent(m.method.Output.GoIdent), ")")
+		g.P("if err := c.client.Call(ctx, ", strconv.Quote(m.rule.Method.String()), ", ", strconv.Quote(m.rule.GetPath()), ", in, out); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return out, nil")
+		g.P("}")
+		g.P()
+	}
+}
+
+func methodSignature(g *protogen.GeneratedFile, method *protogen.Method) string {
+	return method.GoName + "(ctx " + g.QualifiedGoIdent(contextPackage.Ident("Context")) +
+		", in *" + g.QualifiedGoIdent(method.Input.GoIdent) +
+		") (*" + g.QualifiedGoIdent(method.Output.GoIdent) + ", error)"
+}
+
+func unexport(s string) string {
+	return string(s[0]+'a'-'A') + s[1:]
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// fileProtos returns the file and its dependencies, dependencies first.
+func fileProtos(fd protoreflect.FileDescriptor, seen map[string]bool) []*descriptorpb.FileDescriptorProto {
+	if seen[fd.Path()] {
+		return nil
+	}
+	seen[fd.Path()] = true
+	var files []*descriptorpb.FileDescriptorProto
+	for i := 0; i < fd.Imports().Len(); i++ {
+		files = append(files, fileProtos(fd.Imports().Get(i).FileDescriptor, seen)...)
+	}
+	return append(files, protodesc.ToFileDescriptorProto(fd))
+}
+
+func TestGenerateFile(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{test.File_test_proto.Path()},
+		Parameter:      proto.String("paths=source_relative"),
+		ProtoFile:      fileProtos(test.File_test_proto, map[string]bool{}),
+	}
+	gen, err := protogen.Options{}.New(req)
+	require.NoError(t, err)
+	for _, f := range gen.Files {
+		if f.Generate {
+			generateFile(gen, f)
+		}
+	}
+	res := gen.Response()
+	require.Nil(t, res.Error)
+	require.Len(t, res.File, 1)
+	require.Equal(t, "test_ghb.pb.go", res.File[0].GetName())
+
+	golden := "testdata/test_ghb.pb.go.golden"
+	if *update {
+		require.NoError(t, os.WriteFile(golden, []byte(res.File[0].GetContent()), 0o644))
+	}
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), res.File[0].GetContent())
+}
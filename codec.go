@@ -0,0 +1,185 @@
+package ghb
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeJSON     = "application/json"
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeProto    = "application/protobuf"
+)
+
+// Codec converts between a wire format and the generic values ghb builds from
+// messages using the json_name field rules. Messages are represented as
+// map[string]any, repeated fields as []any and scalars as their go values.
+// Unmarshal is given a pointer to a map[string]any to decode the message into,
+// numbers may be decoded as any go numeric type or as strings.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// messageCodec encodes and decodes the messages of requests and responses
+// with a media type, either through a Codec or as binary protobuf.
+type messageCodec interface {
+	marshal(msg proto.Message) ([]byte, error)
+	unmarshal(body []byte, msg proto.Message, params map[string]string, limits decodeLimits) error
+}
+
+func defaultCodecs() map[string]messageCodec {
+	return map[string]messageCodec{
+		contentTypeJSON:     valueCodec{jsonCodec{}},
+		contentTypeProtobuf: protoCodec{},
+		contentTypeProto:    protoCodec{},
+	}
+}
+
+// RegisterCodec registers the codec used for requests and responses with the
+// given media type, replacing any codec registered for it before. It must be
+// called before Serve.
+func (s *Server) RegisterCodec(mediaType string, c Codec) {
+	s.codecs[strings.ToLower(mediaType)] = valueCodec{c}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// valueCodec adapts a Codec to the field mapping of marshalMessage and
+// unmarshalMessage.
+type valueCodec struct {
+	Codec
+}
+
+func (c valueCodec) marshal(msg proto.Message) ([]byte, error) {
+	return marshalBytesWith(c.Codec, msg)
+}
+
+func (c valueCodec) unmarshal(body []byte, msg proto.Message, params map[string]string, limits decodeLimits) error {
+	return unmarshalBytesWith(c.Codec, body, msg, params, limits)
+}
+
+type protoCodec struct{}
+
+func (protoCodec) marshal(msg proto.Message) ([]byte, error) {
+	return proto.Marshal(msg)
+}
+
+// unmarshal applies the path params first and merges the body on top of them
+// so the body takes precedence, just like it does for JSON.
+func (protoCodec) unmarshal(body []byte, msg proto.Message, params map[string]string, limits decodeLimits) error {
+	if err := unmarshalBytes(nil, msg, params); err != nil {
+		return err
+	}
+	opts := proto.UnmarshalOptions{Merge: true, RecursionLimit: limits.maxDepth}
+	if err := opts.Unmarshal(body, msg); err != nil {
+		return err
+	}
+	return limits.checkMessage(msg.ProtoReflect())
+}
+
+// requestDecoder decodes the body of a request into a message, the path
+// params are applied first so that the body takes precedence over them.
+type requestDecoder interface {
+	decode(r *http.Request, msg proto.Message, params map[string]string) error
+}
+
+// bodyDecoder reads the whole body before handing it to the codec.
+type bodyDecoder struct {
+	messageCodec
+	limits decodeLimits
+}
+
+func (d bodyDecoder) decode(r *http.Request, msg proto.Message, params map[string]string) error {
+	var body []byte
+	var err error
+	if r.Body != nil && r.ContentLength != 0 {
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+	}
+	return d.unmarshal(body, msg, params, d.limits)
+}
+
+// requestDecoder picks the decoder for the request body from its
+// Content-Type, a missing Content-Type is treated as JSON.
+func (s *Server) requestDecoder(r *http.Request) (requestDecoder, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = contentTypeJSON
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	switch mediaType {
+	case contentTypeForm:
+		return formDecoder{limits: s.limits}, true
+	case contentTypeMultipart:
+		return s.multipartDecoder(), true
+	}
+	c, ok := s.codecs[mediaType]
+	if !ok {
+		return nil, false
+	}
+	return bodyDecoder{messageCodec: c, limits: s.limits}, true
+}
+
+// responseCodec picks the codec of the response from the Accept header, the
+// first supported media type with the highest quality wins. The codec leaves
+// the fields marked omit_from_body out of the body.
+func (s *Server) responseCodec(r *http.Request) (string, messageCodec, bool) {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		accept = contentTypeJSON
+	}
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+		if match := s.matchContentType(mediaType); match != "" {
+			best, bestQ = match, q
+		}
+	}
+	if best == "" {
+		return "", nil, false
+	}
+	return best, omittingCodec{s.codecs[best]}, true
+}
+
+func (s *Server) matchContentType(mediaType string) string {
+	switch mediaType {
+	case "*/*", "application/*":
+		return contentTypeJSON
+	}
+	if _, ok := s.codecs[mediaType]; ok {
+		return mediaType
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package ghb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+)
+
+// lineCodec encodes flat messages as "key=value" lines, numbers are decoded as
+// ints to make sure non JSON codecs are not tied to float64.
+type lineCodec struct{}
+
+func (lineCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected map, got %T", v)
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s=%v\n", k, m[k])
+	}
+	return buf.Bytes(), nil
+}
+
+func (lineCodec) Unmarshal(data []byte, v any) error {
+	m := *(v.(*map[string]any))
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("invalid line %q", line)
+		}
+		if n, err := strconv.Atoi(value); err == nil {
+			m[key] = n
+			continue
+		}
+		m[key] = value
+	}
+	return nil
+}
+
+func TestServer_RegisterCodec(t *testing.T) {
+	s := NewServer()
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	s.RegisterCodec("text/x-lines", lineCodec{})
+	addr := serveTest(t, s)
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/v1/users", strings.NewReader("id=u1\nname=Jane\nage=28\n"))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "text/x-lines")
+	req.Header.Set("Accept", "text/x-lines")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/x-lines", res.Header.Get("Content-Type"))
+	require.Equal(t, "age=28\nid=u1\nname=Jane\n", string(body))
+}
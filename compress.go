@@ -0,0 +1,235 @@
+package ghb
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	errBodyTooLarge        = errors.New("request body too large")
+	errUnsupportedEncoding = errors.New("unsupported content encoding")
+)
+
+// Compressor implements a content coding used for the Content-Encoding of
+// request bodies and the Accept-Encoding of responses.
+type Compressor interface {
+	NewWriter(w io.Writer) io.WriteCloser
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+func defaultCompressors() map[string]Compressor {
+	return map[string]Compressor{
+		"gzip":    gzipCompressor{},
+		"deflate": deflateCompressor{},
+	}
+}
+
+// RegisterCompressor registers the compressor for the given content coding,
+// replacing any compressor registered for it before. It must be called
+// before Serve.
+func (s *Server) RegisterCompressor(name string, c Compressor) {
+	name = strings.ToLower(name)
+	if _, ok := s.compressors[name]; !ok {
+		s.compressorNames = append(s.compressorNames, name)
+	}
+	s.compressors[name] = c
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) NewWriter(w io.Writer) io.WriteCloser {
+	return gzip.NewWriter(w)
+}
+
+func (gzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// deflateCompressor implements the deflate content coding, which despite its
+// name is the zlib format.
+type deflateCompressor struct{}
+
+func (deflateCompressor) NewWriter(w io.Writer) io.WriteCloser {
+	return zlib.NewWriter(w)
+}
+
+func (deflateCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+// decompressRequest replaces the body of a request with a Content-Encoding
+// by its decompressed form, capped at the maximum decompressed size. Codings
+// without a compressor fail with errUnsupportedEncoding.
+func (s *Server) decompressRequest(r *http.Request) (io.Closer, error) {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		return nil, nil
+	}
+	c, ok := s.compressors[encoding]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnsupportedEncoding, encoding)
+	}
+	body, err := c.NewReader(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress request body: %v", err)
+	}
+	r.Body = &maxBytesReader{ReadCloser: body, n: s.maxDecompressedSize}
+	r.ContentLength = -1
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	return body, nil
+}
+
+// maxBytesReader fails with errBodyTooLarge once more than n bytes are read.
+type maxBytesReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.n < 0 {
+		return 0, errBodyTooLarge
+	}
+	if int64(len(p)) > r.n+1 {
+		p = p[:r.n+1]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.n -= int64(n)
+	if r.n < 0 {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
+
+// responseCompressor picks the compressor for the response from the
+// Accept-Encoding header, the first registered coding with the highest
+// quality wins.
+func (s *Server) responseCompressor(r *http.Request) (string, Compressor) {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if name == "*" && len(s.compressorNames) > 0 {
+			name = s.compressorNames[0]
+		}
+		if _, ok := s.compressors[name]; ok && q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	if best == "" {
+		return "", nil
+	}
+	return best, s.compressors[best]
+}
+
+// compressResponseWriter buffers the response until it is known whether it
+// is at least threshold bytes long and worth compressing.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	name      string
+	c         Compressor
+	threshold int
+
+	code    int
+	buf     []byte
+	decided bool
+	cw      io.WriteCloser
+}
+
+func newCompressResponseWriter(w http.ResponseWriter, name string, c Compressor, threshold int) *compressResponseWriter {
+	return &compressResponseWriter{ResponseWriter: w, name: name, c: c, threshold: threshold, code: http.StatusOK}
+}
+
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.code = code
+}
+
+func (w *compressResponseWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.threshold {
+			return len(p), nil
+		}
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.cw != nil {
+		return w.cw.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		w.start()
+	}
+	if f, ok := w.cw.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// close writes what is still buffered and finishes the compressed stream.
+func (w *compressResponseWriter) close() error {
+	if !w.decided {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+	if w.cw != nil {
+		return w.cw.Close()
+	}
+	return nil
+}
+
+func (w *compressResponseWriter) start() error {
+	w.decided = true
+	header := w.Header()
+	// the response could have been compressed for another Accept-Encoding,
+	// caches must tell them apart even when it is sent as is.
+	header.Add("Vary", "Accept-Encoding")
+	if len(w.buf) >= w.threshold && header.Get("Content-Encoding") == "" && bodyAllowed(w.code) {
+		header.Set("Content-Encoding", w.name)
+		header.Del("Content-Length")
+		w.cw = w.c.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.cw != nil {
+		_, err = w.cw.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+func bodyAllowed(code int) bool {
+	return code >= http.StatusOK && code != http.StatusNoContent && code != http.StatusNotModified
+}
@@ -0,0 +1,102 @@
+package ghb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestServer_compression(t *testing.T) {
+	s := NewServer(WithMaxDecompressedSize(4096))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+	do := func(t *testing.T, body []byte, header http.Header) (*http.Response, []byte) {
+		req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/v1/users", bytes.NewReader(body))
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, data
+	}
+	longName := strings.Repeat("a", 2000)
+	large := []byte(`{"id": "1", "name": "` + longName + `"}`)
+
+	t.Run("gzip response", func(t *testing.T) {
+		res, body := do(t, large, http.Header{"Accept-Encoding": {"deflate;q=0.5, gzip"}})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+		require.Equal(t, "Accept-Encoding", res.Header.Get("Vary"))
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		data, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id": "1", "name": "`+longName+`", "age": 0}`, string(data))
+	})
+
+	t.Run("deflate response", func(t *testing.T) {
+		res, body := do(t, large, http.Header{"Accept-Encoding": {"deflate"}})
+		require.Equal(t, "deflate", res.Header.Get("Content-Encoding"))
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		_, err = io.ReadAll(zr)
+		require.NoError(t, err)
+	})
+
+	t.Run("below threshold", func(t *testing.T) {
+		res, body := do(t, []byte(`{"id": "1"}`), http.Header{"Accept-Encoding": {"gzip"}})
+		require.Equal(t, "", res.Header.Get("Content-Encoding"))
+		require.Equal(t, "Accept-Encoding", res.Header.Get("Vary"))
+		require.JSONEq(t, `{"id": "1", "name": "", "age": 0}`, string(body))
+	})
+
+	t.Run("gzip request", func(t *testing.T) {
+		res, body := do(t, gzipBytes(t, []byte(`{"id": "1", "name": "Jane"}`)), http.Header{"Content-Encoding": {"gzip"}})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.JSONEq(t, `{"id": "1", "name": "Jane", "age": 0}`, string(body))
+	})
+
+	t.Run("decompressed request too large", func(t *testing.T) {
+		huge := []byte(`{"id": "1", "name": "` + strings.Repeat("a", 8192) + `"}`)
+		res, _ := do(t, gzipBytes(t, huge), http.Header{"Content-Encoding": {"gzip"}})
+		require.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+	})
+
+	t.Run("corrupt request body", func(t *testing.T) {
+		res, _ := do(t, []byte("not gzip"), http.Header{"Content-Encoding": {"gzip"}})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		corrupt := gzipBytes(t, large)
+		corrupt[len(corrupt)/2] ^= 0xff
+		res, _ = do(t, corrupt, http.Header{"Content-Encoding": {"gzip"}})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("unsupported request encoding", func(t *testing.T) {
+		res, _ := do(t, large, http.Header{"Content-Encoding": {"br"}})
+		require.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+	})
+}
@@ -0,0 +1,431 @@
+package ghb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	contentTypeConnectPrefix = "application/connect+"
+
+	connectCompressedFlag = 0x01
+	connectEndStreamFlag  = 0x02
+)
+
+// connectCodes are the names of the codes in the Connect protocol.
+var connectCodes = map[codes.Code]string{
+	codes.Canceled:           "canceled",
+	codes.Unknown:            "unknown",
+	codes.InvalidArgument:    "invalid_argument",
+	codes.DeadlineExceeded:   "deadline_exceeded",
+	codes.NotFound:           "not_found",
+	codes.AlreadyExists:      "already_exists",
+	codes.PermissionDenied:   "permission_denied",
+	codes.ResourceExhausted:  "resource_exhausted",
+	codes.FailedPrecondition: "failed_precondition",
+	codes.Aborted:            "aborted",
+	codes.OutOfRange:         "out_of_range",
+	codes.Unimplemented:      "unimplemented",
+	codes.Internal:           "internal",
+	codes.Unavailable:        "unavailable",
+	codes.DataLoss:           "data_loss",
+	codes.Unauthenticated:    "unauthenticated",
+}
+
+// isConnectStream reports whether r is a request of a Connect streaming call,
+// whose messages are compressed one by one rather than as a whole body.
+func isConnectStream(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeConnectPrefix)
+}
+
+// handleConnectService serves every method of the service with the Connect
+// protocol at POST /package.Service/Method, whether it has an http rule or
+// not. Methods served already are skipped.
+func (s *Server) handleConnectService(info *serviceInfo, service protoreflect.ServiceDescriptor) {
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+		path := fullMethodName(method)
+		if _, ok := s.routes[routeKey(path)]; ok {
+			continue
+		}
+		if methodDesc, ok := info.methods[string(method.Name())]; ok {
+			s.handle(http.MethodPost, path, s.connectUnaryHandler(info.impl, path, methodDesc))
+			continue
+		}
+		if streamDesc, ok := info.streams[string(method.Name())]; ok {
+			s.handle(http.MethodPost, path, s.connectStreamHandler(info.impl, path, streamDesc))
+		}
+	}
+}
+
+// connectCodec returns the codec for a Connect media type, e.g.
+// application/json or application/connect+proto for streams.
+func (s *Server) connectCodec(r *http.Request, stream bool) (string, messageCodec, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "", nil, false
+	}
+	name, ok := strings.CutPrefix(mediaType, "application/")
+	if stream {
+		name, ok = strings.CutPrefix(mediaType, contentTypeConnectPrefix)
+	}
+	if !ok {
+		return "", nil, false
+	}
+	if name == "proto" {
+		return mediaType, protoCodec{}, true
+	}
+	c, ok := s.codecs["application/"+name]
+	return mediaType, c, ok
+}
+
+// connectContext returns the context of a Connect call, with the request
+// headers as incoming metadata and the deadline of Connect-Timeout-Ms.
+func connectContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	if v := r.Header.Get("Connect-Protocol-Version"); v != "" && v != "1" {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "connect-protocol-version %q is not supported", v)
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), incomingMetadata(r))
+	timeout := r.Header.Get("Connect-Timeout-Ms")
+	if timeout == "" {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+	ms, err := strconv.ParseInt(timeout, 10, 64)
+	if err != nil || ms < 0 || len(timeout) > 10 {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "invalid connect-timeout-ms %q", timeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+	return ctx, cancel, nil
+}
+
+// connectUnaryHandler serves a unary method, the request and the response are
+// the body encoded with the codec of the Content-Type.
+func (s *Server) connectUnaryHandler(impl any, fullMethod string, methodDesc *grpc.MethodDesc) http.HandlerFunc {
+	interceptor := s.unaryInterceptor()
+	return func(w http.ResponseWriter, r *http.Request) {
+		contentType, c, ok := s.connectCodec(r, false)
+		if !ok {
+			unsupportedMediaType(w, r.Header.Get("Content-Type"))
+			return
+		}
+		sts := &unaryTransportStream{method: fullMethod}
+		ctx, cancel, err := connectContext(r)
+		if err != nil {
+			writeConnectError(w, sts, err)
+			return
+		}
+		defer cancel()
+		// the body is read after any Content-Encoding is undone, so this
+		// bounds the decompressed message even if the body size is not.
+		maxSize := s.maxMessageSize()
+		if r.ContentLength > maxSize {
+			writeConnectError(w, sts, errBodyTooLarge)
+			return
+		}
+		r.Body = &maxBytesReader{ReadCloser: r.Body, n: maxSize}
+		dec := func(in any) error {
+			msg, ok := in.(proto.Message)
+			if !ok {
+				return errUnsupportedType(in)
+			}
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				if errors.Is(err, errBodyTooLarge) {
+					return status.Error(codes.ResourceExhausted, err.Error())
+				}
+				return status.Errorf(codes.InvalidArgument, "failed to read request: %v", err)
+			}
+			if len(body) == 0 {
+				body = nil
+			}
+			if err := c.unmarshal(body, msg, nil, s.limits); err != nil {
+				return status.Errorf(codes.InvalidArgument, "failed to unmarshal request: %v", err)
+			}
+			return nil
+		}
+		ctx = grpc.NewContextWithServerTransportStream(ctx, sts)
+		res, err := methodDesc.Handler(impl, ctx, dec, interceptor)
+		if err != nil {
+			writeConnectError(w, sts, err)
+			return
+		}
+		msg, ok := res.(proto.Message)
+		if !ok {
+			writeConnectError(w, sts, status.Errorf(codes.Internal, "wrong type %T, expected proto message", res))
+			return
+		}
+		body, err := c.marshal(msg)
+		if err != nil {
+			writeConnectError(w, sts, status.Errorf(codes.Internal, "failed to marshal response: %v", err))
+			return
+		}
+		sts.writeMetadata(w.Header())
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
+
+// connectError is the JSON encoding of an error in the Connect protocol.
+type connectError struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message,omitempty"`
+	Details []connectErrorDetail `json:"details,omitempty"`
+}
+
+// connectErrorDetail is a detail of an error, the value is the base64 encoded
+// message with the full name type.
+type connectErrorDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func newConnectError(err error) *connectError {
+	st := status.Convert(err)
+	e := &connectError{Code: connectCodes[st.Code()], Message: st.Message()}
+	if e.Code == "" {
+		e.Code = connectCodes[codes.Unknown]
+	}
+	for _, d := range st.Proto().GetDetails() {
+		name := d.GetTypeUrl()[strings.LastIndexByte(d.GetTypeUrl(), '/')+1:]
+		e.Details = append(e.Details, connectErrorDetail{
+			Type:  name,
+			Value: base64.RawStdEncoding.EncodeToString(d.GetValue()),
+		})
+	}
+	return e
+}
+
+// writeConnectError writes the error of a unary call, the metadata set by the
+// handler is sent as headers.
+func writeConnectError(w http.ResponseWriter, sts *unaryTransportStream, err error) {
+	code := httpStatusFromCode(status.Code(err))
+	if errors.Is(err, errBodyTooLarge) {
+		err = status.Error(codes.ResourceExhausted, err.Error())
+		code = http.StatusRequestEntityTooLarge
+	}
+	body, merr := json.Marshal(newConnectError(err))
+	if merr != nil {
+		internalServerError(w, merr)
+		return
+	}
+	sts.writeMetadata(w.Header())
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+// unaryTransportStream lets unary handlers set the header and trailer
+// metadata with grpc.SetHeader and grpc.SetTrailer, which are all sent as
+// headers of the response, the trailers prefixed with Trailer-.
+type unaryTransportStream struct {
+	method string
+
+	mu      sync.Mutex
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (t *unaryTransportStream) Method() string {
+	return t.method
+}
+
+func (t *unaryTransportStream) SetHeader(md metadata.MD) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.header = metadata.Join(t.header, md)
+	return nil
+}
+
+// SendHeader sets the header metadata, the headers are only sent along with
+// the response.
+func (t *unaryTransportStream) SendHeader(md metadata.MD) error {
+	return t.SetHeader(md)
+}
+
+func (t *unaryTransportStream) SetTrailer(md metadata.MD) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trailer = metadata.Join(t.trailer, md)
+	return nil
+}
+
+func (t *unaryTransportStream) writeMetadata(header http.Header) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, values := range headerFromMetadata(t.header) {
+		header[key] = values
+	}
+	for key, values := range headerFromMetadata(t.trailer) {
+		header[http.CanonicalHeaderKey("Trailer-"+key)] = values
+	}
+}
+
+// connectStreamHandler serves a streaming method, the messages are enveloped
+// and the stream ends with an end-stream message carrying the status and the
+// trailer metadata.
+func (s *Server) connectStreamHandler(impl any, fullMethod string, streamDesc *grpc.StreamDesc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		contentType, c, ok := s.connectCodec(r, true)
+		if !ok {
+			unsupportedMediaType(w, r.Header.Get("Content-Type"))
+			return
+		}
+		stream := &connectStream{
+			httpResponseStream: httpResponseStream{
+				w:           w,
+				contentType: contentType,
+				header:      metadata.MD{},
+			},
+			codec:  c,
+			limits: s.limits,
+		}
+		ctx, cancel, err := connectContext(r)
+		if err != nil {
+			stream.finish(err)
+			return
+		}
+		defer cancel()
+		stream.ctx = ctx
+		if encoding := r.Header.Get("Connect-Content-Encoding"); encoding != "" && encoding != "identity" {
+			if stream.compressor = s.compressors[strings.ToLower(encoding)]; stream.compressor == nil {
+				stream.finish(status.Errorf(codes.Unimplemented, "connect-content-encoding %q is not supported", encoding))
+				return
+			}
+		}
+		stream.body = r.Body
+		stream.maxSize = s.maxMessageSize()
+		// bidi streams read the request while writing the response.
+		http.NewResponseController(w).EnableFullDuplex()
+		stream.finish(s.callStream(impl, fullMethod, streamDesc, stream))
+	}
+}
+
+// connectStream implements grpc.ServerStream for a Connect streaming call.
+type connectStream struct {
+	httpResponseStream
+	body       io.Reader
+	codec      messageCodec
+	compressor Compressor
+	limits     decodeLimits
+	// maxSize bounds every message rather than the body, which has no end
+	// for long lived streams.
+	maxSize int64
+}
+
+func (s *connectStream) SendMsg(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errUnsupportedType(m)
+	}
+	data, err := s.codec.marshal(msg)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to marshal message: %v", err)
+	}
+	s.writeHeader()
+	return s.writeEnvelope(0, data)
+}
+
+func (s *connectStream) RecvMsg(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errUnsupportedType(m)
+	}
+	flag, data, err := readFrame(s.body, s.maxSize)
+	if err != nil {
+		return err
+	}
+	if flag&connectEndStreamFlag != 0 {
+		return io.EOF
+	}
+	if flag&connectCompressedFlag != 0 {
+		if s.compressor == nil {
+			return status.Error(codes.Internal, "compressed message without connect-content-encoding")
+		}
+		if data, err = decompressMessage(s.compressor, data, s.maxSize); err != nil {
+			return err
+		}
+	}
+	if err := s.codec.unmarshal(data, msg, nil, s.limits); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to unmarshal message: %v", err)
+	}
+	return nil
+}
+
+// decompressMessage expands a compressed message of a stream, which fails
+// with ResourceExhausted once it grows past max bytes, unless max is zero.
+func decompressMessage(c Compressor, data []byte, max int64) ([]byte, error) {
+	zr, err := c.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decompress message: %v", err)
+	}
+	defer zr.Close()
+	var r io.Reader = zr
+	if max > 0 {
+		r = &maxBytesReader{ReadCloser: zr, n: max}
+	}
+	data, err = io.ReadAll(r)
+	if errors.Is(err, errBodyTooLarge) {
+		return nil, status.Errorf(codes.ResourceExhausted, "decompressed message exceeds the maximum size of %d bytes", max)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decompress message: %v", err)
+	}
+	return data, nil
+}
+
+func (s *connectStream) writeEnvelope(flag byte, data []byte) error {
+	envelope := make([]byte, 5, 5+len(data))
+	envelope[0] = flag
+	binary.BigEndian.PutUint32(envelope[1:], uint32(len(data)))
+	if _, err := s.w.Write(append(envelope, data...)); err != nil {
+		return err
+	}
+	return http.NewResponseController(s.w).Flush()
+}
+
+// finish writes the end-stream message with the status returned by the
+// handler and the trailer metadata.
+func (s *connectStream) finish(err error) {
+	s.writeHeader()
+	end := struct {
+		Error    *connectError       `json:"error,omitempty"`
+		Metadata map[string][]string `json:"metadata,omitempty"`
+	}{}
+	if err != nil {
+		if errors.Is(err, errBodyTooLarge) {
+			err = status.Error(codes.ResourceExhausted, err.Error())
+		}
+		end.Error = newConnectError(err)
+	}
+	s.mu.Lock()
+	if len(s.trailer) > 0 {
+		end.Metadata = headerFromMetadata(s.trailer)
+	}
+	s.mu.Unlock()
+	data, merr := json.Marshal(end)
+	if merr != nil {
+		data = []byte(`{"error":{"code":"internal"}}`)
+	}
+	s.writeEnvelope(connectEndStreamFlag, data)
+}
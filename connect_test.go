@@ -0,0 +1,244 @@
+package ghb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func postConnect(t *testing.T, addr, method, contentType string, body []byte, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr+method, bytes.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Connect-Protocol-Version", "1")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, data
+}
+
+func connectEnvelope(flag byte, data string) []byte {
+	envelope := []byte{flag}
+	envelope = binary.BigEndian.AppendUint32(envelope, uint32(len(data)))
+	return append(envelope, data...)
+}
+
+// readEnvelopes returns the messages of a Connect stream response and the
+// end-stream message.
+func readEnvelopes(t *testing.T, body []byte) ([]string, string) {
+	t.Helper()
+	r := bytes.NewReader(body)
+	var messages []string
+	for {
+		flag, data, err := readFrame(r, 0)
+		require.NoError(t, err)
+		if flag&connectEndStreamFlag != 0 {
+			_, _, err := readFrame(r, 0)
+			require.Equal(t, io.EOF, err)
+			return messages, string(data)
+		}
+		messages = append(messages, string(data))
+	}
+}
+
+func TestServer_connectUnary(t *testing.T) {
+	addr := newTestServer(t)
+
+	res, body := postConnect(t, addr, "/ghb.test.TestService/GetUser", contentTypeJSON, []byte(`{"id": "123"}`), nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, contentTypeJSON, res.Header.Get("Content-Type"))
+	require.JSONEq(t, `{"id": "123", "name": "John Doe", "age": 30}`, string(body))
+
+	req, err := proto.Marshal(&test.GetUserRequest{Id: "7"})
+	require.NoError(t, err)
+	res, body = postConnect(t, addr, "/ghb.test.TestService/GetUser", "application/proto", req, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/proto", res.Header.Get("Content-Type"))
+	user := &test.TestUser{}
+	require.NoError(t, proto.Unmarshal(body, user))
+	require.Equal(t, "7", user.Id)
+
+	// methods without an http rule are served as well, with their metadata.
+	res, body = postConnect(t, addr, "/ghb.test.TestService/Ping", contentTypeJSON, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.JSONEq(t, `{}`, string(body))
+	require.Equal(t, "1", res.Header.Get("X-Pong"))
+	require.Equal(t, "ghb", res.Header.Get("Trailer-X-Served-By"))
+}
+
+func TestServer_connectUnaryErrors(t *testing.T) {
+	addr := newTestServer(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		header      http.Header
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "status",
+			method:      "/ghb.test.TestService/GetUser",
+			contentType: contentTypeJSON,
+			body:        `{"id": "missing"}`,
+			wantStatus:  http.StatusNotFound,
+			wantBody:    `{"code": "not_found", "message": "user not found"}`,
+		},
+		{
+			name:        "invalid body",
+			method:      "/ghb.test.TestService/GetUser",
+			contentType: contentTypeJSON,
+			body:        `{"id": 1`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"code": "invalid_argument", "message": "failed to unmarshal request: failed to unmarshal request body: unexpected end of JSON input"}`,
+		},
+		{
+			name:        "invalid timeout",
+			method:      "/ghb.test.TestService/GetUser",
+			contentType: contentTypeJSON,
+			body:        `{"id": "123"}`,
+			header:      http.Header{"Connect-Timeout-Ms": {"soon"}},
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"code": "invalid_argument", "message": "invalid connect-timeout-ms \"soon\""}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := postConnect(t, addr, tt.method, tt.contentType, []byte(tt.body), tt.header)
+			require.Equal(t, tt.wantStatus, res.StatusCode)
+			require.JSONEq(t, tt.wantBody, string(body))
+		})
+	}
+
+	res, _ := postConnect(t, addr, "/ghb.test.TestService/GetUser", "application/xml", nil, nil)
+	require.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+	res, _ = postConnect(t, addr, "/ghb.test.TestService/Download", contentTypeJSON, nil, nil)
+	require.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+}
+
+func TestServer_connectStream(t *testing.T) {
+	addr := newTestServer(t)
+
+	res, body := postConnect(t, addr, "/ghb.test.TestService/Download", "application/connect+json", connectEnvelope(0, `{"format": "csv"}`), nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/connect+json", res.Header.Get("Content-Type"))
+	messages, end := readEnvelopes(t, body)
+	require.Len(t, messages, 3)
+	require.JSONEq(t, `{"content_type": "text/csv", "data": "aWQsbmFtZQo=", "extensions": []}`, messages[0])
+	require.JSONEq(t, `{}`, end)
+
+	_, body = postConnect(t, addr, "/ghb.test.TestService/Download", "application/connect+json", connectEnvelope(0, `{"format": "broken"}`), nil)
+	messages, end = readEnvelopes(t, body)
+	require.Len(t, messages, 3)
+	require.JSONEq(t, `{"error": {"code": "data_loss", "message": "export interrupted"}}`, end)
+
+	upload := append(connectEnvelope(0, `{"folder": "docs", "name": "a.txt"}`), connectEnvelope(0, `{"folder": "docs", "name": "b.txt"}`)...)
+	_, body = postConnect(t, addr, "/ghb.test.TestService/Upload", "application/connect+json", upload, nil)
+	messages, end = readEnvelopes(t, body)
+	require.Len(t, messages, 1)
+	require.JSONEq(t, `{"files": ["docs/a.txt", "docs/b.txt"], "size": 0, "checksum": "00000000"}`, messages[0])
+	require.JSONEq(t, `{}`, end)
+
+	_, body = postConnect(t, addr, "/ghb.test.TestService/Upload", "application/connect+json", connectEnvelope(0, `{"name": 1`), nil)
+	_, end = readEnvelopes(t, body)
+	require.Contains(t, end, `"code":"invalid_argument"`)
+}
+
+func TestServer_connectDecompressedSize(t *testing.T) {
+	s := NewServer(WithMaxBodySize(1024))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	// a few bytes of gzip expand past the limit once decompressed.
+	bomb := `{"folder": "docs", "name": "` + strings.Repeat("a", 64<<10) + `"}`
+	compressed := gzipBytes(t, []byte(bomb))
+	require.Less(t, len(compressed), 1024)
+
+	header := http.Header{"Connect-Content-Encoding": {"gzip"}}
+	_, body := postConnect(t, addr, "/ghb.test.TestService/Upload", "application/connect+json", connectEnvelope(connectCompressedFlag, string(compressed)), header)
+	_, end := readEnvelopes(t, body)
+	require.Contains(t, end, `"code":"resource_exhausted"`)
+
+	header = http.Header{"Content-Encoding": {"gzip"}}
+	_, body = postConnect(t, addr, "/ghb.test.TestService/GetUser", contentTypeJSON, compressed, header)
+	require.Contains(t, string(body), `"code":"resource_exhausted"`)
+}
+
+func TestServer_interceptors(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+	unary := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			record(name + " " + info.FullMethod)
+			return handler(ctx, req)
+		}
+	}
+	stream := func(ss any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		record("stream " + info.FullMethod)
+		return handler(ss, stream)
+	}
+	s := NewServer(WithUnaryInterceptors(unary("first"), unary("second")), WithStreamInterceptors(stream))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	res, err := http.Get("http://" + addr + "/v1/users/123")
+	require.NoError(t, err)
+	res.Body.Close()
+	res, _ = postConnect(t, addr, "/ghb.test.TestService/Ping", contentTypeJSON, []byte(`{}`), nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	postGRPCWeb(t, addr, "/ghb.test.TestService/Download", contentTypeGRPCWeb, grpcWebFrame(t, &test.ExportRequest{Format: "csv"}))
+	res, err = http.Get("http://" + addr + "/v1/downloads/csv")
+	require.NoError(t, err)
+	res.Body.Close()
+
+	require.Equal(t, []string{
+		"first /ghb.test.TestService/GetUser",
+		"second /ghb.test.TestService/GetUser",
+		"first /ghb.test.TestService/Ping",
+		"second /ghb.test.TestService/Ping",
+		"stream /ghb.test.TestService/Download",
+		"stream /ghb.test.TestService/Download",
+	}, calls)
+}
+
+func Test_newConnectError(t *testing.T) {
+	st, err := status.New(codes.NotFound, "user not found").WithDetails(&errdetails.ResourceInfo{ResourceName: "users/1"})
+	require.NoError(t, err)
+	detail, err := proto.Marshal(&errdetails.ResourceInfo{ResourceName: "users/1"})
+	require.NoError(t, err)
+	require.Equal(t, &connectError{
+		Code:    "not_found",
+		Message: "user not found",
+		Details: []connectErrorDetail{{Type: "google.rpc.ResourceInfo", Value: base64.RawStdEncoding.EncodeToString(detail)}},
+	}, newConnectError(st.Err()))
+
+	require.Equal(t, &connectError{Code: "unknown", Message: "boom"}, newConnectError(errors.New("boom")))
+}
@@ -0,0 +1,99 @@
+package ghb
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy configures how a Server answers cross-origin requests.
+type CORSPolicy struct {
+	// AllowedOrigins lists the origins allowed to make cross-origin requests,
+	// entries may be patterns as understood by path.Match, e.g.
+	// "https://*.example.com", and "*" allows any origin. As it would let
+	// any site make requests on behalf of the user, "*" is ignored if
+	// AllowCredentials is set, the origins must be listed then.
+	AllowedOrigins []string
+	// AllowedMethods lists the methods allowed by preflights, defaults to the
+	// methods registered for the requested path.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers allowed by preflights, "*"
+	// allows any requested header.
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers readable by the client.
+	ExposedHeaders []string
+	// AllowCredentials allows requests with cookies or authorization.
+	AllowCredentials bool
+	// MaxAge is how long the result of a preflight may be cached.
+	MaxAge time.Duration
+}
+
+// allowOrigin returns the value of Access-Control-Allow-Origin for origin,
+// false if the origin is not allowed.
+func (p *CORSPolicy) allowOrigin(origin string) (string, bool) {
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" {
+			if p.AllowCredentials {
+				continue
+			}
+			return "*", true
+		}
+		if ok, _ := path.Match(strings.ToLower(allowed), origin); ok {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// setHeaders sets the headers of an actual cross-origin response, it returns
+// false if the request has no allowed origin.
+func (p *CORSPolicy) setHeaders(header http.Header, r *http.Request) bool {
+	header.Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	allowed, ok := p.allowOrigin(origin)
+	if !ok {
+		return false
+	}
+	header.Set("Access-Control-Allow-Origin", allowed)
+	if p.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(p.ExposedHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+	}
+	return true
+}
+
+// preflight answers a preflight for a path registered for the given methods,
+// the CORS headers of the origin are already set by ServeHTTP.
+func (s *Server) preflight(w http.ResponseWriter, r *http.Request, methods []string) {
+	header := w.Header()
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	if len(s.cors.AllowedMethods) > 0 {
+		methods = s.cors.AllowedMethods
+	}
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if headers := s.allowedHeaders(r); headers != "" {
+		header.Set("Access-Control-Allow-Headers", headers)
+	}
+	if s.cors.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(s.cors.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) allowedHeaders(r *http.Request) string {
+	for _, h := range s.cors.AllowedHeaders {
+		if h == "*" {
+			return r.Header.Get("Access-Control-Request-Headers")
+		}
+	}
+	return strings.Join(s.cors.AllowedHeaders, ", ")
+}
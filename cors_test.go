@@ -0,0 +1,89 @@
+package ghb
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_cors(t *testing.T) {
+	s := NewServer(WithCORS(CORSPolicy{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedHeaders:   []string{"Content-Type"},
+		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	do := func(t *testing.T, method, path string, header http.Header) *http.Response {
+		req, err := http.NewRequest(method, "http://"+addr+path, nil)
+		require.NoError(t, err)
+		req.Header = header
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	t.Run("preflight", func(t *testing.T) {
+		res := do(t, http.MethodOptions, "/v1/users", http.Header{
+			"Origin":                         {"https://app.example.com"},
+			"Access-Control-Request-Method":  {"POST"},
+			"Access-Control-Request-Headers": {"content-type"},
+		})
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		require.Equal(t, "https://app.example.com", res.Header.Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "true", res.Header.Get("Access-Control-Allow-Credentials"))
+		require.Equal(t, "POST", res.Header.Get("Access-Control-Allow-Methods"))
+		require.Equal(t, "Content-Type", res.Header.Get("Access-Control-Allow-Headers"))
+		require.Equal(t, "3600", res.Header.Get("Access-Control-Max-Age"))
+		require.Contains(t, res.Header.Values("Vary"), "Origin")
+	})
+	t.Run("preflight of a path with wildcards", func(t *testing.T) {
+		res := do(t, http.MethodOptions, "/v1/users/123", http.Header{
+			"Origin":                        {"https://app.example.com"},
+			"Access-Control-Request-Method": {"GET"},
+		})
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		require.Equal(t, "GET, DELETE", res.Header.Get("Access-Control-Allow-Methods"))
+	})
+	t.Run("origin not allowed", func(t *testing.T) {
+		res := do(t, http.MethodOptions, "/v1/users", http.Header{
+			"Origin":                        {"https://example.org"},
+			"Access-Control-Request-Method": {"POST"},
+		})
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		require.Empty(t, res.Header.Get("Access-Control-Allow-Origin"))
+		require.Empty(t, res.Header.Get("Access-Control-Allow-Methods"))
+	})
+	t.Run("actual request", func(t *testing.T) {
+		res := do(t, http.MethodGet, "/v1/users/123", http.Header{"Origin": {"https://app.example.com"}})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "https://app.example.com", res.Header.Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "X-Request-Id", res.Header.Get("Access-Control-Expose-Headers"))
+	})
+}
+
+func Test_allowOrigin(t *testing.T) {
+	p := &CORSPolicy{AllowedOrigins: []string{"*"}}
+	allowed, ok := p.allowOrigin("https://app.example.com")
+	require.True(t, ok)
+	require.Equal(t, "*", allowed)
+
+	// any origin with credentials is never allowed.
+	p = &CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	_, ok = p.allowOrigin("https://evil.example")
+	require.False(t, ok)
+
+	p = &CORSPolicy{AllowedOrigins: []string{"*", "https://*.example.com"}, AllowCredentials: true}
+	allowed, ok = p.allowOrigin("https://App.example.com")
+	require.True(t, ok)
+	require.Equal(t, "https://app.example.com", allowed)
+	_, ok = p.allowOrigin("https://evil.example")
+	require.False(t, ok)
+}
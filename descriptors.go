@@ -0,0 +1,102 @@
+package ghb
+
+import (
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// LoadFileDescriptorSet reads a google.protobuf.FileDescriptorSet, as written
+// by protoc --descriptor_set_out or buf build. Imports missing from the set
+// are resolved from protoregistry.GlobalFiles.
+func LoadFileDescriptorSet(path string) (*protoregistry.Files, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return newFiles(set.GetFile())
+}
+
+// RegisterProxyFiles serves the http rules of every service in files by
+// forwarding the calls to cc, see RegisterProxy. The requests and responses
+// are dynamic messages unless their types are linked into the binary.
+func (s *Server) RegisterProxyFiles(files *protoregistry.Files, cc grpc.ClientConnInterface) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		for i := 0; i < fd.Services().Len(); i++ {
+			sd := fd.Services().Get(i)
+			if err := s.checkRegistration(string(sd.FullName()), nil, nil); err != nil {
+				s.registrationFailed(err)
+				continue
+			}
+			s.registerProxy(sd, cc)
+		}
+		return true
+	})
+}
+
+// newFiles builds the file descriptors, registering the dependencies of each
+// file before the file itself.
+func newFiles(fdps []*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	files := new(protoregistry.Files)
+	pending := make(map[string]*descriptorpb.FileDescriptorProto, len(fdps))
+	for _, fdp := range fdps {
+		pending[fdp.GetName()] = fdp
+	}
+	var register func(path string) error
+	register = func(path string) error {
+		fdp, ok := pending[path]
+		if !ok {
+			// registered already, or left to the resolver.
+			return nil
+		}
+		delete(pending, path)
+		for _, dep := range fdp.GetDependency() {
+			if err := register(dep); err != nil {
+				return err
+			}
+		}
+		fd, err := protodesc.NewFile(fdp, filesResolver{files})
+		if err != nil {
+			return err
+		}
+		return files.RegisterFile(fd)
+	}
+	for _, fdp := range fdps {
+		if err := register(fdp.GetName()); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// filesResolver resolves descriptors from files, then from the descriptors
+// linked into the binary.
+type filesResolver struct {
+	files *protoregistry.Files
+}
+
+func (r filesResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := r.files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r filesResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := r.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
@@ -0,0 +1,94 @@
+package ghb
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// newTestGreeter serves the greeter service of testdata/greeter.binpb with a
+// grpc server handling the calls with dynamic messages. register adds other
+// services to the server.
+func newTestGreeter(t *testing.T, files *protoregistry.Files, register func(*grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+	desc, err := files.FindDescriptorByName("ghb.greeter.Greeter")
+	require.NoError(t, err)
+	method := desc.(protoreflect.ServiceDescriptor).Methods().ByName("Greet")
+
+	backend := grpc.NewServer(grpc.UnknownServiceHandler(func(srv any, stream grpc.ServerStream) error {
+		name, _ := grpc.MethodFromServerStream(stream)
+		if name != "/ghb.greeter.Greeter/Greet" {
+			return status.Errorf(codes.Unimplemented, "unknown method %s", name)
+		}
+		req := dynamicpb.NewMessage(method.Input())
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		greeting := req.Get(method.Input().Fields().ByName("greeting")).String()
+		if greeting == "" {
+			greeting = "hello"
+		}
+		res := dynamicpb.NewMessage(method.Output())
+		res.Set(method.Output().Fields().ByName("message"), protoreflect.ValueOfString(greeting+" "+req.Get(method.Input().Fields().ByName("name")).String()))
+		return stream.SendMsg(res)
+	}))
+	if register != nil {
+		register(backend)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go backend.Serve(lis)
+	t.Cleanup(backend.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestServer_proxyFiles(t *testing.T) {
+	files, err := LoadFileDescriptorSet("testdata/greeter.binpb")
+	require.NoError(t, err)
+
+	s := NewServer()
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	s.RegisterProxyFiles(files, newTestGreeter(t, files, nil))
+	addr := serveTest(t, s)
+
+	res, err := http.Get("http://" + addr + "/v1/greet/jane?greeting=hi")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.JSONEq(t, `{"message": "hi jane"}`, string(body))
+
+	// the services linked into the binary are served as well.
+	res, err = http.Get("http://" + addr + "/v1/users/123")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestLoadFileDescriptorSet(t *testing.T) {
+	files, err := LoadFileDescriptorSet("testdata/greeter.binpb")
+	require.NoError(t, err)
+	_, err = files.FindDescriptorByName("ghb.greeter.GreetRequest")
+	require.NoError(t, err)
+
+	_, err = LoadFileDescriptorSet("testdata/greeter.proto")
+	require.Error(t, err)
+	_, err = LoadFileDescriptorSet("testdata/missing.binpb")
+	require.Error(t, err)
+}
@@ -1,12 +1,45 @@
 package ghb
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// writeError writes err as a JSON encoded google.rpc.Status, the http status
+// code is derived from the grpc code of the error.
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errBodyTooLarge) {
+		writeStatus(w, http.StatusRequestEntityTooLarge, status.New(codes.ResourceExhausted, err.Error()))
+		return
+	}
+	st := status.Convert(err)
+	writeStatus(w, httpStatusFromCode(st.Code()), st)
+}
+
+func writeStatus(w http.ResponseWriter, code int, st *status.Status) {
+	body, err := marshalBytes(st.Proto())
+	if err != nil {
+		http.Error(w, st.Message(), code)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+// errUnsupportedType is returned by streams and codecs given a value which is
+// not a proto message.
+func errUnsupportedType(v any) error {
+	return fmt.Errorf("unsupported type: %T", v)
+}
+
 func badRequest(w http.ResponseWriter, err error) {
-	http.Error(w, err.Error(), http.StatusBadRequest)
+	writeStatus(w, http.StatusBadRequest, status.New(codes.InvalidArgument, err.Error()))
 }
 
 func badRequestf(w http.ResponseWriter, format string, a ...any) {
@@ -15,9 +48,86 @@ func badRequestf(w http.ResponseWriter, format string, a ...any) {
 }
 
 func internalServerError(w http.ResponseWriter, err error) {
-	http.Error(w, err.Error(), http.StatusInternalServerError)
+	writeStatus(w, http.StatusInternalServerError, status.New(codes.Internal, err.Error()))
 }
 
 func internalServerErrorf(w http.ResponseWriter, format string, a ...any) {
 	internalServerError(w, fmt.Errorf("internal server error: "+format, a...))
 }
+
+func unsupportedMediaType(w http.ResponseWriter, contentType string) {
+	writeStatus(w, http.StatusUnsupportedMediaType, status.Newf(codes.InvalidArgument, "unsupported content type %q", contentType))
+}
+
+func notAcceptable(w http.ResponseWriter, accept string) {
+	writeStatus(w, http.StatusNotAcceptable, status.Newf(codes.InvalidArgument, "none of the accepted content types %q are supported", accept))
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusNotFound, status.Newf(codes.NotFound, "no route for %s %s", r.Method, r.URL.Path))
+}
+
+func methodNotAllowed(w http.ResponseWriter, method, allow string) {
+	w.Header().Set("Allow", allow)
+	writeStatus(w, http.StatusMethodNotAllowed, status.Newf(codes.Unimplemented, "method %s not allowed, allowed are %s", method, allow))
+}
+
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// codeFromHTTPStatus is the inverse of httpStatusFromCode, used for error
+// responses without a status body.
+func codeFromHTTPStatus(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest, http.StatusNotAcceptable, http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case 499:
+		return codes.Canceled
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusTooManyRequests, http.StatusRequestEntityTooLarge:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented, http.StatusMethodNotAllowed:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	if code >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
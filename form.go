@@ -0,0 +1,157 @@
+package ghb
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	contentTypeForm      = "application/x-www-form-urlencoded"
+	contentTypeMultipart = "multipart/form-data"
+)
+
+// formDecoder decodes application/x-www-form-urlencoded bodies, keys are
+// dotted paths of json names e.g. "address.zipCode" or "labels.env".
+type formDecoder struct {
+	limits decodeLimits
+}
+
+func (d formDecoder) decode(r *http.Request, msg proto.Message, params map[string]string) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return fmt.Errorf("failed to parse form: %v", err)
+	}
+	form := make(map[string][]any, len(values))
+	for key, vs := range values {
+		for _, v := range vs {
+			form[key] = append(form[key], v)
+		}
+	}
+	return unmarshalForm(msg, form, params, d.limits)
+}
+
+// multipartDecoder decodes multipart/form-data bodies, plain parts are
+// handled like form fields and file parts are bound as bytes.
+type multipartDecoder struct {
+	maxPartSize int64
+	limits      decodeLimits
+}
+
+func (s *Server) multipartDecoder() multipartDecoder {
+	return multipartDecoder{maxPartSize: s.maxFormPartSize, limits: s.limits}
+}
+
+func (d multipartDecoder) decode(r *http.Request, msg proto.Message, params map[string]string) error {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+	form := make(map[string][]any)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name, value, err := d.readPart(part)
+		if err != nil {
+			return err
+		}
+		form[name] = append(form[name], value)
+	}
+	return unmarshalForm(msg, form, params, d.limits)
+}
+
+// readPart returns the form name and value of the part, the contents of a
+// file part as []byte, otherwise as a string.
+func (d multipartDecoder) readPart(part *multipart.Part) (string, any, error) {
+	defer part.Close()
+	name := part.FormName()
+	if name == "" {
+		return "", nil, fmt.Errorf("multipart part without a form name")
+	}
+	data, err := io.ReadAll(io.LimitReader(part, d.maxPartSize+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if int64(len(data)) > d.maxPartSize {
+		return "", nil, fmt.Errorf("part %q exceeds the maximum size of %d bytes", name, d.maxPartSize)
+	}
+	if part.FileName() != "" {
+		return name, data, nil
+	}
+	return name, string(data), nil
+}
+
+func unmarshalForm(msg proto.Message, form map[string][]any, params map[string]string, limits decodeLimits) error {
+	value := make(map[string]any, len(params)+len(form))
+	for k, v := range params {
+		value[k] = v
+	}
+	md := msg.ProtoReflect().Descriptor()
+	for key, vs := range form {
+		path := strings.Split(key, ".")
+		if err := limits.checkDepth(len(path)); err != nil {
+			return fmt.Errorf("form field %s: %v", key, err)
+		}
+		if err := setFormValue(md, value, path, vs); err != nil {
+			return fmt.Errorf("form field %s: %v", key, err)
+		}
+	}
+	if err := limits.checkValue(value); err != nil {
+		return err
+	}
+	return unmarshalMessage(msg, value)
+}
+
+// setFormValue sets the values of the dotted path in value, the descriptor is
+// used to tell lists apart from single values which forms can not express.
+func setFormValue(md protoreflect.MessageDescriptor, value map[string]any, path []string, vs []any) error {
+	keys, err := descriptorKeys(md)
+	if err != nil {
+		return err
+	}
+	protoKey, ok := keys[path[0]]
+	if !ok {
+		return fmt.Errorf("field %s not found", path[0])
+	}
+	fd := md.Fields().ByName(protoreflect.Name(protoKey))
+
+	switch {
+	case len(path) == 1 && fd.IsList():
+		list, _ := value[path[0]].([]any)
+		value[path[0]] = append(list, vs...)
+	case len(path) == 1:
+		value[path[0]] = vs[len(vs)-1]
+	case len(path) == 2 && fd.IsMap():
+		mp, ok := value[path[0]].(map[string]any)
+		if !ok {
+			mp = map[string]any{}
+			value[path[0]] = mp
+		}
+		mp[path[1]] = vs[len(vs)-1]
+	case fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap():
+		nested, ok := value[path[0]].(map[string]any)
+		if !ok {
+			nested = map[string]any{}
+			value[path[0]] = nested
+		}
+		return setFormValue(fd.Message(), nested, path[1:], vs)
+	default:
+		return fmt.Errorf("field %s can not be set with a dotted path", path[0])
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package ghb
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_unmarshalForm(t *testing.T) {
+	tests := []struct {
+		name     string
+		form     map[string][]any
+		params   map[string]string
+		expected *test.Profile
+		isErr    bool
+	}{
+		{
+			name: "dotted paths and json names",
+			form: map[string][]any{
+				"displayName":     {"Jane"},
+				"tags":            {"a", "b"},
+				"address.city":    {"Berlin"},
+				"address.zipCode": {"10115"},
+				"labels.env":      {"prod"},
+			},
+			params: map[string]string{"id": "42"},
+			expected: &test.Profile{
+				Id:          "42",
+				DisplayName: "Jane",
+				Tags:        []string{"a", "b"},
+				Address:     &test.Address{City: "Berlin", ZipCode: "10115"},
+				Labels:      map[string]string{"env": "prod"},
+			},
+		},
+		{
+			name: "file contents bound to bytes",
+			form: map[string][]any{
+				"avatar": {[]byte{0x89, 0x50, 0x4e, 0x47}},
+			},
+			expected: &test.Profile{
+				Avatar: []byte{0x89, 0x50, 0x4e, 0x47},
+			},
+		},
+		{
+			name:  "unknown field",
+			form:  map[string][]any{"address.country": {"DE"}},
+			isErr: true,
+		},
+		{
+			name:  "dotted path into a scalar",
+			form:  map[string][]any{"displayName.first": {"Jane"}},
+			isErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := &test.Profile{}
+			err := unmarshalForm(actual, tt.form, tt.params, decodeLimits{})
+			if tt.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.EqualExportedValues(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestServer_formBodies(t *testing.T) {
+	s := NewServer(WithMaxFormPartSize(16))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	post := func(t *testing.T, path, contentType string, body io.Reader) (int, string) {
+		res, err := http.Post("http://"+addr+path, contentType, body)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(data)
+	}
+
+	t.Run("urlencoded", func(t *testing.T) {
+		form := url.Values{"displayName": {"Jane"}, "address.zipCode": {"10115"}}
+		code, body := post(t, "/v1/profiles/42", contentTypeForm, strings.NewReader(form.Encode()))
+		require.Equal(t, http.StatusOK, code)
+		require.JSONEq(t, `{"id": "42", "displayName": "Jane", "avatar": null, "tags": [], "address": {"city": "", "zipCode": "10115"}, "labels": {}}`, body)
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		mw.WriteField("displayName", "Jane")
+		fw, _ := mw.CreateFormFile("avatar", "avatar.png")
+		fw.Write([]byte("png"))
+		mw.Close()
+		code, body := post(t, "/v1/profiles/42", mw.FormDataContentType(), &buf)
+		require.Equal(t, http.StatusOK, code)
+		require.JSONEq(t, `{"id": "42", "displayName": "Jane", "avatar": "cG5n", "tags": [], "labels": {}}`, body)
+	})
+
+	t.Run("part too large", func(t *testing.T) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		fw, _ := mw.CreateFormFile("avatar", "avatar.png")
+		fw.Write(bytes.Repeat([]byte("x"), 17))
+		mw.Close()
+		code, _ := post(t, "/v1/profiles/42", mw.FormDataContentType(), &buf)
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("client streaming upload", func(t *testing.T) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		mw.WriteField("name", "a.txt")
+		fw, _ := mw.CreateFormFile("data", "a.txt")
+		fw.Write([]byte("hello"))
+		mw.WriteField("name", "b.txt")
+		fw, _ = mw.CreateFormFile("data", "b.txt")
+		fw.Write([]byte("world!"))
+		mw.Close()
+		code, body := post(t, "/v1/uploads/docs", mw.FormDataContentType(), &buf)
+		require.Equal(t, http.StatusOK, code)
+		require.JSONEq(t, `{"files": ["docs/a.txt", "docs/b.txt"], "size": 11}`, body)
+	})
+}
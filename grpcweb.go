@@ -0,0 +1,293 @@
+package ghb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeGRPCWeb     = "application/grpc-web"
+	contentTypeGRPCWebText = "application/grpc-web-text"
+
+	grpcWebCompressedFlag = 0x01
+	grpcWebTrailerFlag    = 0x80
+)
+
+// grpcWebContentType returns the media type of a gRPC-Web request, the
+// message codec suffix included, e.g. application/grpc-web+proto.
+func grpcWebContentType(r *http.Request) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "", false
+	}
+	base, _, _ := strings.Cut(mediaType, "+")
+	if base != contentTypeGRPCWeb && base != contentTypeGRPCWebText {
+		return "", false
+	}
+	return mediaType, true
+}
+
+// serveGRPCWeb serves a gRPC-Web request for a unary or server streaming
+// method of a registered service, with the binary protobuf codec. The status
+// is always sent in a trailer frame after the messages.
+func (s *Server) serveGRPCWeb(w http.ResponseWriter, r *http.Request, mediaType string) {
+	base, subtype, _ := strings.Cut(mediaType, "+")
+	stream := &grpcWebStream{
+		httpResponseStream: httpResponseStream{
+			w:           w,
+			contentType: base + "+proto",
+			header:      metadata.MD{},
+		},
+		text:    base == contentTypeGRPCWebText,
+		limits:  s.limits,
+		maxSize: s.maxMessageSize(),
+	}
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r.Method, http.MethodPost)
+		return
+	}
+	if subtype != "" && subtype != "proto" {
+		unsupportedMediaType(w, r.Header.Get("Content-Type"))
+		return
+	}
+	method, err := s.lookupMethod(r.URL.Path)
+	if err != nil {
+		stream.finish(err)
+		return
+	}
+	stream.method = method.name
+	if encoding := r.Header.Get("Grpc-Encoding"); encoding != "" && encoding != "identity" {
+		if stream.compressor = s.compressors[strings.ToLower(encoding)]; stream.compressor == nil {
+			stream.finish(status.Errorf(codes.Unimplemented, "grpc-encoding %q is not supported", encoding))
+			return
+		}
+	}
+	if method.desc.IsStreamingClient() {
+		stream.finish(status.Errorf(codes.Unimplemented, "client streaming method %s is not supported by grpc-web", method.name))
+		return
+	}
+	ctx, cancel, err := grpcContext(r)
+	if err != nil {
+		stream.finish(err)
+		return
+	}
+	defer cancel()
+	stream.ctx = ctx
+	if s.maxBodySize > 0 {
+		if r.ContentLength > s.maxBodySize {
+			stream.finish(status.Error(codes.ResourceExhausted, errBodyTooLarge.Error()))
+			return
+		}
+		r.Body = &maxBytesReader{ReadCloser: r.Body, n: s.maxBodySize}
+	}
+	stream.body = r.Body
+	if stream.text {
+		stream.body = base64.NewDecoder(base64.StdEncoding, r.Body)
+	}
+
+	if method.stream != nil {
+		stream.finish(s.callStream(method.impl, method.name, method.stream, stream))
+		return
+	}
+	ctx = grpc.NewContextWithServerTransportStream(ctx, grpcWebTransportStream{stream})
+	res, err := method.unary.Handler(method.impl, ctx, stream.RecvMsg, s.unaryInterceptor())
+	if err == nil {
+		err = stream.SendMsg(res)
+	}
+	stream.finish(err)
+}
+
+// grpcContext returns the context of a call, with the request headers as
+// incoming metadata and the deadline of the Grpc-Timeout header.
+func grpcContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	ctx := metadata.NewIncomingContext(r.Context(), incomingMetadata(r))
+	timeout := r.Header.Get("Grpc-Timeout")
+	if timeout == "" {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+	d, err := parseTimeout(timeout)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return ctx, cancel, nil
+}
+
+// grpcWebStream implements grpc.ServerStream for a gRPC-Web request, which
+// carries a single length-prefixed message. Every message sent is written
+// and flushed as a frame of its own, base64 encoded for grpc-web-text.
+type grpcWebStream struct {
+	httpResponseStream
+	method     string
+	body       io.Reader
+	text       bool
+	compressor Compressor
+	limits     decodeLimits
+	maxSize    int64
+	received   bool
+}
+
+func (s *grpcWebStream) SendMsg(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errUnsupportedType(m)
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to marshal message: %v", err)
+	}
+	s.writeHeader()
+	return s.writeFrame(0, data)
+}
+
+func (s *grpcWebStream) RecvMsg(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errUnsupportedType(m)
+	}
+	if s.received {
+		return io.EOF
+	}
+	s.received = true
+	flag, data, err := readFrame(s.body, s.maxSize)
+	if err == io.EOF {
+		// a request without a frame is an empty message.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if flag&grpcWebCompressedFlag != 0 {
+		if s.compressor == nil {
+			return status.Error(codes.Internal, "compressed message without grpc-encoding")
+		}
+		if data, err = decompressMessage(s.compressor, data, s.maxSize); err != nil {
+			return err
+		}
+	}
+	if err := (protoCodec{}).unmarshal(data, msg, nil, s.limits); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to unmarshal request: %v", err)
+	}
+	return nil
+}
+
+// readFrame reads a length-prefixed frame of a flag byte, the big endian
+// length of the data and the data, as used by gRPC-Web and Connect streams.
+// Frames larger than max fail unless max is zero.
+func readFrame(r io.Reader, max int64) (byte, []byte, error) {
+	var head [5]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		if err == io.EOF {
+			return 0, nil, io.EOF
+		}
+		return 0, nil, frameReadError(err)
+	}
+	length := binary.BigEndian.Uint32(head[1:])
+	if max > 0 && int64(length) > max {
+		return 0, nil, frameReadError(errBodyTooLarge)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, frameReadError(err)
+	}
+	return head[0], data, nil
+}
+
+func frameReadError(err error) error {
+	if errors.Is(err, errBodyTooLarge) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return status.Errorf(codes.InvalidArgument, "failed to read message: %v", err)
+}
+
+func (s *grpcWebStream) writeFrame(flag byte, data []byte) error {
+	frame := make([]byte, 5, 5+len(data))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	frame = append(frame, data...)
+	if s.text {
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+	if _, err := s.w.Write(frame); err != nil {
+		return err
+	}
+	return http.NewResponseController(s.w).Flush()
+}
+
+// finish writes the status returned by the handler and the trailer metadata
+// in the trailer frame.
+func (s *grpcWebStream) finish(err error) {
+	s.writeHeader()
+	st := status.Convert(err)
+	trailer := metadata.Join(s.trailer, metadata.Pairs("grpc-status", strconv.Itoa(int(st.Code()))))
+	if st.Message() != "" {
+		trailer.Set("grpc-message", encodeGRPCMessage(st.Message()))
+	}
+	header := headerFromMetadata(trailer)
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, key := range keys {
+		for _, v := range header[key] {
+			fmt.Fprintf(&buf, "%s: %s\r\n", strings.ToLower(key), v)
+		}
+	}
+	s.writeFrame(grpcWebTrailerFlag, buf.Bytes())
+}
+
+// encodeGRPCMessage percent-encodes the grpc-message as in the grpc protocol.
+func encodeGRPCMessage(msg string) string {
+	var sb strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			sb.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+	return sb.String()
+}
+
+// grpcWebTransportStream lets unary handlers set the header and trailer
+// metadata with grpc.SetHeader and grpc.SetTrailer.
+type grpcWebTransportStream struct {
+	stream *grpcWebStream
+}
+
+func (t grpcWebTransportStream) Method() string {
+	return t.stream.method
+}
+
+func (t grpcWebTransportStream) SetHeader(md metadata.MD) error {
+	return t.stream.SetHeader(md)
+}
+
+func (t grpcWebTransportStream) SendHeader(md metadata.MD) error {
+	return t.stream.SendHeader(md)
+}
+
+func (t grpcWebTransportStream) SetTrailer(md metadata.MD) error {
+	t.stream.SetTrailer(md)
+	return nil
+}
@@ -0,0 +1,212 @@
+package ghb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/protobuf/proto"
+)
+
+func grpcWebFrame(t *testing.T, msg proto.Message) []byte {
+	t.Helper()
+	data, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	frame := []byte{0}
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(data)))
+	return append(frame, data...)
+}
+
+// postGRPCWeb calls the method and returns the messages and the trailers of
+// the response.
+func postGRPCWeb(t *testing.T, addr, method, contentType string, body []byte) ([][]byte, string) {
+	t.Helper()
+	res, err := http.Post("http://"+addr+method, contentType, bytes.NewReader(body))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	var r io.Reader = bytes.NewReader(data)
+	if strings.HasPrefix(contentType, contentTypeGRPCWebText) {
+		require.Equal(t, contentTypeGRPCWebText+"+proto", res.Header.Get("Content-Type"))
+		// every frame is encoded on its own, the padding ends a chunk.
+		var decoded []byte
+		for chunk := string(data); chunk != ""; {
+			end := strings.IndexByte(chunk, '=')
+			if end < 0 {
+				end = len(chunk)
+			}
+			for end < len(chunk) && chunk[end] == '=' {
+				end++
+			}
+			b, err := base64.StdEncoding.DecodeString(chunk[:end])
+			require.NoError(t, err)
+			decoded = append(decoded, b...)
+			chunk = chunk[end:]
+		}
+		r = bytes.NewReader(decoded)
+	} else {
+		require.Equal(t, contentTypeGRPCWeb+"+proto", res.Header.Get("Content-Type"))
+	}
+	var messages [][]byte
+	for {
+		flag, data, err := readFrame(r, 0)
+		require.NoError(t, err)
+		if flag&grpcWebTrailerFlag != 0 {
+			return messages, string(data)
+		}
+		messages = append(messages, data)
+	}
+}
+
+func TestServer_grpcWeb(t *testing.T) {
+	addr := newTestServer(t)
+	const getUser = "/ghb.test.TestService/GetUser"
+
+	messages, trailer := postGRPCWeb(t, addr, getUser, contentTypeGRPCWeb, grpcWebFrame(t, &test.GetUserRequest{Id: "123"}))
+	require.Len(t, messages, 1)
+	user := &test.TestUser{}
+	require.NoError(t, proto.Unmarshal(messages[0], user))
+	require.EqualExportedValues(t, &test.TestUser{Id: "123", Name: "John Doe", Age: 30}, user)
+	require.Equal(t, "grpc-status: 0\r\n", trailer)
+
+	text := base64.StdEncoding.EncodeToString(grpcWebFrame(t, &test.GetUserRequest{Id: "7"}))
+	messages, trailer = postGRPCWeb(t, addr, getUser, contentTypeGRPCWebText, []byte(text))
+	require.Len(t, messages, 1)
+	require.NoError(t, proto.Unmarshal(messages[0], user))
+	require.Equal(t, "7", user.Id)
+	require.Equal(t, "grpc-status: 0\r\n", trailer)
+
+	messages, trailer = postGRPCWeb(t, addr, getUser, contentTypeGRPCWeb+"+proto", grpcWebFrame(t, &test.GetUserRequest{Id: "missing"}))
+	require.Empty(t, messages)
+	require.Equal(t, "grpc-message: user not found\r\ngrpc-status: 5\r\n", trailer)
+}
+
+func TestServer_grpcWebServerStream(t *testing.T) {
+	addr := newTestServer(t)
+	const download = "/ghb.test.TestService/Download"
+
+	messages, trailer := postGRPCWeb(t, addr, download, contentTypeGRPCWeb, grpcWebFrame(t, &test.ExportRequest{Format: "csv"}))
+	require.Len(t, messages, 3)
+	chunk := &httpbody.HttpBody{}
+	require.NoError(t, proto.Unmarshal(messages[1], chunk))
+	require.Equal(t, "1,Jane\n", string(chunk.Data))
+	require.Equal(t, "grpc-status: 0\r\n", trailer)
+
+	messages, trailer = postGRPCWeb(t, addr, download, contentTypeGRPCWebText, []byte(base64.StdEncoding.EncodeToString(grpcWebFrame(t, &test.ExportRequest{Format: "broken"}))))
+	require.Len(t, messages, 3)
+	require.Equal(t, "grpc-message: export interrupted\r\ngrpc-status: 15\r\n", trailer)
+}
+
+func TestServer_grpcWebErrors(t *testing.T) {
+	addr := newTestServer(t)
+
+	tests := []struct {
+		name    string
+		method  string
+		body    []byte
+		trailer string
+	}{
+		{
+			name:    "unknown service",
+			method:  "/ghb.test.Unknown/GetUser",
+			trailer: "grpc-message: unknown service ghb.test.Unknown\r\ngrpc-status: 12\r\n",
+		},
+		{
+			name:    "unknown method",
+			method:  "/ghb.test.TestService/Unknown",
+			trailer: "grpc-message: unknown method Unknown for service ghb.test.TestService\r\ngrpc-status: 12\r\n",
+		},
+		{
+			name:    "client streaming",
+			method:  "/ghb.test.TestService/Upload",
+			trailer: "grpc-message: client streaming method /ghb.test.TestService/Upload is not supported by grpc-web\r\ngrpc-status: 12\r\n",
+		},
+		{
+			name:    "truncated frame",
+			method:  "/ghb.test.TestService/GetUser",
+			body:    []byte{0, 0, 0, 0, 10, 1},
+			trailer: "grpc-message: failed to read message: unexpected EOF\r\ngrpc-status: 3\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, trailer := postGRPCWeb(t, addr, tt.method, contentTypeGRPCWeb, tt.body)
+			require.Empty(t, messages)
+			require.Equal(t, tt.trailer, trailer)
+		})
+	}
+}
+
+func TestServer_grpcWebFrameLimit(t *testing.T) {
+	s := NewServer(WithMaxBodySize(0))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	// the length of the frame is checked before its data is allocated.
+	body := []byte{0, 0xff, 0xff, 0xff, 0xf0, 1}
+	messages, trailer := postGRPCWeb(t, addr, "/ghb.test.TestService/GetUser", contentTypeGRPCWeb, body)
+	require.Empty(t, messages)
+	require.Equal(t, "grpc-message: "+errBodyTooLarge.Error()+"\r\ngrpc-status: 8\r\n", trailer)
+}
+
+func TestServer_grpcWebDecompressedSize(t *testing.T) {
+	s := NewServer(WithMaxBodySize(1024))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	// a few bytes of gzip expand past the limit once decompressed.
+	data, err := proto.Marshal(&test.GetUserRequest{Id: strings.Repeat("a", 64<<10)})
+	require.NoError(t, err)
+	compressed := gzipBytes(t, data)
+	require.Less(t, len(compressed), 1024)
+	body := []byte{grpcWebCompressedFlag}
+	body = binary.BigEndian.AppendUint32(body, uint32(len(compressed)))
+	body = append(body, compressed...)
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/ghb.test.TestService/GetUser", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentTypeGRPCWeb)
+	req.Header.Set("Grpc-Encoding", "gzip")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	flag, trailer, err := readFrame(res.Body, 0)
+	require.NoError(t, err)
+	require.NotZero(t, flag&grpcWebTrailerFlag)
+	require.Equal(t, "grpc-message: decompressed message exceeds the maximum size of 1024 bytes\r\ngrpc-status: 8\r\n", string(trailer))
+}
+
+func TestServer_grpcWebPreflight(t *testing.T) {
+	s := NewServer(WithCORS(CORSPolicy{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}}))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	req, err := http.NewRequest(http.MethodOptions, "http://"+addr+"/ghb.test.TestService/GetUser", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	require.Equal(t, "*", res.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, http.MethodPost, res.Header.Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "content-type,x-grpc-web", res.Header.Get("Access-Control-Allow-Headers"))
+}
+
+func Test_encodeGRPCMessage(t *testing.T) {
+	require.Equal(t, "user not found", encodeGRPCMessage("user not found"))
+	require.Equal(t, "100%25 done%0A", encodeGRPCMessage("100% done\n"))
+	require.Equal(t, "caf%C3%A9", encodeGRPCMessage("café"))
+}
@@ -0,0 +1,100 @@
+package ghb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+)
+
+// serveHealthz serves the liveness probe, the status of the service of the
+// service query parameter, or of the whole server without it.
+func (s *Server) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	s.serveHealth(w, r, false)
+}
+
+// serveReadyz serves the readiness probe, like the liveness probe except
+// that the server is not ready once Shutdown is called.
+func (s *Server) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	s.serveHealth(w, r, true)
+}
+
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request, readiness bool) {
+	res := &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}
+	if !readiness || !s.shuttingDown.Load() {
+		var err error
+		res, err = s.health.Check(r.Context(), &grpc_health_v1.HealthCheckRequest{
+			Service: r.URL.Query().Get("service"),
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	body, err := marshalBytes(res)
+	if err != nil {
+		internalServerError(w, err)
+		return
+	}
+	code := http.StatusOK
+	if res.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+// serve serves the connections of lis with handler until Shutdown.
+func (s *Server) serve(lis net.Listener, handler http.Handler) error {
+	srv := &http.Server{Handler: handler}
+	s.mu.Lock()
+	if s.shuttingDown.Load() {
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	}
+	s.httpServers = append(s.httpServers, srv)
+	s.mu.Unlock()
+	return srv.Serve(lis)
+}
+
+// Shutdown gracefully stops the server. The readiness probe reports the
+// server as not ready, while the liveness probe and the health server are
+// left alone so the server is not restarted while it drains. After the delay
+// of WithShutdownDelay, Serve stops accepting connections and Shutdown waits
+// for the active requests to complete, as http.Server.Shutdown does. If ctx
+// is done before the delay is over, the connections are closed right away.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.shuttingDown.Store(true)
+	servers := s.httpServers
+	s.mu.Unlock()
+	if s.shutdownDelay > 0 {
+		timer := time.NewTimer(s.shutdownDelay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			// too late to drain, the connections are closed right away.
+			errs := []error{ctx.Err()}
+			for _, srv := range servers {
+				errs = append(errs, srv.Close())
+			}
+			return errors.Join(errs...)
+		}
+	}
+	var errs []error
+	for _, srv := range servers {
+		errs = append(errs, srv.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
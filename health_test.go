@@ -0,0 +1,115 @@
+package ghb
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func getProbe(t *testing.T, addr, path string) (int, string) {
+	t.Helper()
+	res, err := http.Get("http://" + addr + path)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, string(body)
+}
+
+func TestServer_health(t *testing.T) {
+	hs := health.NewServer()
+	hs.SetServingStatus("ghb.test.TestService", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	s := NewServer(WithHealth(hs))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	s.RegisterService(&grpc_health_v1.Health_ServiceDesc, hs)
+	addr := serveTest(t, s)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"Liveness", "/healthz", http.StatusOK, `{"status": 1}`},
+		{"Readiness", "/readyz", http.StatusOK, `{"status": 1}`},
+		{"Not serving", "/readyz?service=ghb.test.TestService", http.StatusServiceUnavailable, `{"status": 2}`},
+		{"Unknown service", "/healthz?service=unknown", http.StatusNotFound, `{"code": 5, "message": "unknown service", "details": []}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := getProbe(t, addr, tt.path)
+			require.Equal(t, tt.wantStatus, code)
+			require.JSONEq(t, tt.wantBody, body)
+		})
+	}
+
+	t.Run("Bridged", func(t *testing.T) {
+		res, body := postConnect(t, addr, "/grpc.health.v1.Health/Check", contentTypeJSON, []byte(`{"service": "ghb.test.TestService"}`), nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.JSONEq(t, `{"status": 2}`, string(body))
+	})
+}
+
+func TestServer_shutdown(t *testing.T) {
+	hs := health.NewServer()
+	s := NewServer(WithHealth(hs), WithShutdownDelay(100*time.Millisecond))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(lis) }()
+	addr := lis.Addr().String()
+
+	code, _ := getProbe(t, addr, "/readyz")
+	require.Equal(t, http.StatusOK, code)
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+	require.Eventually(t, func() bool {
+		res, err := http.Get("http://" + addr + "/readyz")
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+	// the server is still alive while it drains.
+	code, body := getProbe(t, addr, "/healthz")
+	require.Equal(t, http.StatusOK, code)
+	require.JSONEq(t, `{"status": 1}`, body)
+
+	require.NoError(t, <-shutdown)
+	require.ErrorIs(t, <-served, http.ErrServerClosed)
+	require.ErrorIs(t, s.Serve(lis), http.ErrServerClosed)
+}
+
+func TestServer_shutdownDelayExpired(t *testing.T) {
+	s := NewServer(WithShutdownDelay(time.Hour))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(lis) }()
+	addr := lis.Addr().String()
+	code, _ := getProbe(t, addr, "/v1/users/123")
+	require.Equal(t, http.StatusOK, code)
+
+	// the server is closed even though the delay is not over.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, s.Shutdown(ctx), context.DeadlineExceeded)
+	select {
+	case err := <-served:
+		require.ErrorIs(t, err, http.ErrServerClosed)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return")
+	}
+}
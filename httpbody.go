@@ -0,0 +1,91 @@
+package ghb
+
+import (
+	"io"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	httpBodyName           = "google.api.HttpBody"
+	contentTypeOctetStream = "application/octet-stream"
+)
+
+// google.api.HttpBody is matched by name so the generated package isn't
+// needed, and dynamic messages of it work too.
+func isHttpBody(md protoreflect.MessageDescriptor) bool {
+	return md.FullName() == httpBodyName
+}
+
+// httpBodyField returns the first singular field of md holding a
+// google.api.HttpBody, or nil if there is none.
+func httpBodyField(md protoreflect.MessageDescriptor) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() && isHttpBody(fd.Message()) {
+			return fd
+		}
+	}
+	return nil
+}
+
+// hasHttpBody reports whether the raw body of a request or a response is
+// carried by the message instead of being encoded.
+func hasHttpBody(md protoreflect.MessageDescriptor) bool {
+	return isHttpBody(md) || httpBodyField(md) != nil
+}
+
+func setHttpBody(msg protoreflect.Message, contentType string, data []byte) {
+	fields := msg.Descriptor().Fields()
+	msg.Set(fields.ByName("content_type"), protoreflect.ValueOfString(contentType))
+	msg.Set(fields.ByName("data"), protoreflect.ValueOfBytes(data))
+}
+
+func getHttpBody(msg protoreflect.Message) (string, []byte) {
+	fields := msg.Descriptor().Fields()
+	contentType := msg.Get(fields.ByName("content_type")).String()
+	data := msg.Get(fields.ByName("data")).Bytes()
+	return contentType, data
+}
+
+// httpBodyDecoder puts the raw request body into the google.api.HttpBody
+// request, or into its google.api.HttpBody field in which case the other
+// fields are set from the path params.
+type httpBodyDecoder struct{}
+
+func (httpBodyDecoder) decode(r *http.Request, msg proto.Message, params map[string]string) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	m := msg.ProtoReflect()
+	if isHttpBody(m.Descriptor()) {
+		setHttpBody(m, r.Header.Get("Content-Type"), body)
+		return nil
+	}
+	if err := unmarshalBytes(nil, msg, params); err != nil {
+		return err
+	}
+	fd := httpBodyField(m.Descriptor())
+	setHttpBody(m.Mutable(fd).Message(), r.Header.Get("Content-Type"), body)
+	return nil
+}
+
+// writeHttpBody writes the data of a google.api.HttpBody response, or of its
+// google.api.HttpBody field, as is.
+func writeHttpBody(w http.ResponseWriter, msg proto.Message, code int) {
+	m := msg.ProtoReflect()
+	if fd := httpBodyField(m.Descriptor()); fd != nil {
+		m = m.Get(fd).Message()
+	}
+	contentType, data := getHttpBody(m)
+	if contentType == "" {
+		contentType = contentTypeOctetStream
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	w.Write(data)
+}
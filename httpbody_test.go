@@ -0,0 +1,74 @@
+package ghb
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_httpBody(t *testing.T) {
+	addr := newTestServer(t)
+
+	t.Run("response", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/v1/exports/csv", nil)
+		require.NoError(t, err)
+		// not acceptable for a negotiated response, HttpBody skips negotiation.
+		req.Header.Set("Accept", "text/csv")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "text/csv", res.Header.Get("Content-Type"))
+		require.Equal(t, "id,name\n1,Jane\n", string(body))
+	})
+
+	t.Run("response field", func(t *testing.T) {
+		res, err := http.Get("http://" + addr + "/v1/renderings/html")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "text/html", res.Header.Get("Content-Type"))
+		require.Equal(t, "html", res.Header.Get("X-Format"))
+		require.Equal(t, "<p>Jane</p>", string(body))
+	})
+
+	t.Run("request field", func(t *testing.T) {
+		res, err := http.Post("http://"+addr+"/v1/profiles/42/avatar", "image/png", strings.NewReader("png"))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "image/png", res.Header.Get("Content-Type"))
+		require.Equal(t, "42:png", string(body))
+	})
+
+	t.Run("streamed download", func(t *testing.T) {
+		res, err := http.Get("http://" + addr + "/v1/downloads/csv")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "text/csv", res.Header.Get("Content-Type"))
+		require.Equal(t, "id,name\n1,Jane\n2,John\n", string(body))
+		require.Equal(t, "0", res.Trailer.Get("Grpc-Status"))
+	})
+
+	t.Run("streamed download error", func(t *testing.T) {
+		res, err := http.Get("http://" + addr + "/v1/downloads/broken")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		_, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, "15", res.Trailer.Get("Grpc-Status"))
+		require.Equal(t, "export interrupted", res.Trailer.Get("Grpc-Message"))
+	})
+}
@@ -0,0 +1,52 @@
+package ghb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// unaryInterceptor chains the unary interceptors of the server, nil if there
+// are none.
+func (s *Server) unaryInterceptor() grpc.UnaryServerInterceptor {
+	interceptors := s.unaryInterceptors
+	if len(interceptors) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var next func(i int) grpc.UnaryHandler
+		next = func(i int) grpc.UnaryHandler {
+			if i == len(interceptors) {
+				return handler
+			}
+			return func(ctx context.Context, req any) (any, error) {
+				return interceptors[i](ctx, req, info, next(i+1))
+			}
+		}
+		return next(0)(ctx, req)
+	}
+}
+
+// callStream calls the handler of a streaming method through the stream
+// interceptors of the server.
+func (s *Server) callStream(impl any, fullMethod string, desc *grpc.StreamDesc, stream grpc.ServerStream) error {
+	interceptors := s.streamInterceptors
+	if len(interceptors) == 0 {
+		return desc.Handler(impl, stream)
+	}
+	info := &grpc.StreamServerInfo{
+		FullMethod:     fullMethod,
+		IsClientStream: desc.ClientStreams,
+		IsServerStream: desc.ServerStreams,
+	}
+	var next func(i int) grpc.StreamHandler
+	next = func(i int) grpc.StreamHandler {
+		if i == len(interceptors) {
+			return desc.Handler
+		}
+		return func(srv any, stream grpc.ServerStream) error {
+			return interceptors[i](srv, stream, info, next(i+1))
+		}
+	}
+	return next(0)(impl, stream)
+}
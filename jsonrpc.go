@@ -0,0 +1,215 @@
+package ghb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// The error codes defined by JSON-RPC 2.0, the errors returned by the methods
+// use the grpc code as the error code instead.
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+	jsonRPCInternalError  = -32603
+)
+
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	// ID is nil for notifications, which are not answered.
+	ID json.RawMessage `json:"id"`
+}
+
+// jsonRPCResponse is the response of a call, Result is always set on success
+// as the spec requires, and never on error.
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// serveJSONRPC serves a JSON-RPC 2.0 request, or a batch of them, for the
+// unary methods of the registered services. The method of a request is the
+// full name of a method, e.g. "package.Service/Method", and its params are
+// the request message.
+func (s *Server) serveJSONRPC(w http.ResponseWriter, r *http.Request) {
+	if !s.limitBody(w, r, nil) {
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), incomingMetadata(r))
+
+	var res any
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			res = jsonRPCErrorResponse(nil, jsonRPCParseError, err.Error())
+		} else if len(batch) == 0 {
+			res = jsonRPCErrorResponse(nil, jsonRPCInvalidRequest, "empty batch")
+		} else {
+			var responses []*jsonRPCResponse
+			for _, raw := range batch {
+				if res := s.callJSONRPC(ctx, raw); res != nil {
+					responses = append(responses, res)
+				}
+			}
+			if len(responses) > 0 {
+				res = responses
+			}
+		}
+	} else if single := s.callJSONRPC(ctx, body); single != nil {
+		res = single
+	}
+	if res == nil {
+		// only notifications were sent.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		internalServerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.Write(data)
+}
+
+func jsonRPCErrorResponse(id json.RawMessage, code int, message string) *jsonRPCResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &jsonRPCResponse{JSONRPC: "2.0", Error: &jsonRPCError{Code: code, Message: message}, ID: id}
+}
+
+// callJSONRPC calls the method of a single request, the response is nil for
+// notifications.
+func (s *Server) callJSONRPC(ctx context.Context, raw json.RawMessage) *jsonRPCResponse {
+	var req jsonRPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return jsonRPCErrorResponse(nil, jsonRPCParseError, err.Error())
+		}
+		return jsonRPCErrorResponse(nil, jsonRPCInvalidRequest, "invalid request")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" || !validJSONRPCID(req.ID) {
+		return jsonRPCErrorResponse(nil, jsonRPCInvalidRequest, "invalid request")
+	}
+	result, rpcErr := s.invokeJSONRPC(ctx, req)
+	if req.ID == nil {
+		return nil
+	}
+	if rpcErr != nil {
+		return &jsonRPCResponse{JSONRPC: "2.0", Error: rpcErr, ID: req.ID}
+	}
+	return &jsonRPCResponse{JSONRPC: "2.0", Result: result, ID: req.ID}
+}
+
+// validJSONRPCID reports whether the id is a string, a number or null.
+func validJSONRPCID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	var v any
+	if err := json.Unmarshal(id, &v); err != nil {
+		return false
+	}
+	switch v.(type) {
+	case nil, string, float64:
+		return true
+	}
+	return false
+}
+
+func (s *Server) invokeJSONRPC(ctx context.Context, req jsonRPCRequest) (json.RawMessage, *jsonRPCError) {
+	method, err := s.lookupMethod("/" + req.Method)
+	if err != nil {
+		return nil, &jsonRPCError{Code: jsonRPCMethodNotFound, Message: status.Convert(err).Message()}
+	}
+	if method.unary == nil {
+		return nil, &jsonRPCError{Code: jsonRPCMethodNotFound, Message: fmt.Sprintf("streaming method %s is not supported", req.Method)}
+	}
+	var paramsErr error
+	dec := func(in any) error {
+		msg, ok := in.(proto.Message)
+		if !ok {
+			return errUnsupportedType(in)
+		}
+		if paramsErr = s.decodeJSONRPCParams(req.Params, msg); paramsErr != nil {
+			return status.Error(codes.InvalidArgument, paramsErr.Error())
+		}
+		return nil
+	}
+	ctx = grpc.NewContextWithServerTransportStream(ctx, &unaryTransportStream{method: method.name})
+	res, err := method.unary.Handler(method.impl, ctx, dec, s.unaryInterceptor())
+	if paramsErr != nil {
+		return nil, &jsonRPCError{Code: jsonRPCInvalidParams, Message: paramsErr.Error()}
+	}
+	if err != nil {
+		st := status.Convert(err)
+		data, _ := marshalMessage(st.Proto())
+		return nil, &jsonRPCError{Code: int(st.Code()), Message: st.Message(), Data: data}
+	}
+	msg, ok := res.(proto.Message)
+	if !ok {
+		return nil, &jsonRPCError{Code: jsonRPCInternalError, Message: fmt.Sprintf("wrong type %T, expected proto message", res)}
+	}
+	result, err := marshalMessage(msg)
+	if err != nil {
+		return nil, &jsonRPCError{Code: jsonRPCInternalError, Message: err.Error()}
+	}
+	if result == nil {
+		// a nil message is sent as an empty one.
+		return json.RawMessage("{}"), nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, &jsonRPCError{Code: jsonRPCInternalError, Message: err.Error()}
+	}
+	return data, nil
+}
+
+// decodeJSONRPCParams decodes the params, which must be an object by name,
+// into the request message. Missing params leave the message empty.
+func (s *Server) decodeJSONRPCParams(params json.RawMessage, msg proto.Message) error {
+	if params == nil || string(params) == "null" {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(params, &value); err != nil {
+		return err
+	}
+	if _, ok := value.(map[string]any); !ok {
+		return errors.New("params must be an object")
+	}
+	if err := s.limits.checkValue(value); err != nil {
+		return err
+	}
+	return unmarshalMessage(msg, value)
+}
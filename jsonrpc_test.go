@@ -0,0 +1,123 @@
+package ghb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func postJSONRPC(t *testing.T, addr, body string) (*http.Response, string) {
+	t.Helper()
+	res, err := http.Post("http://"+addr+"/rpc", contentTypeJSON, strings.NewReader(body))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, string(data)
+}
+
+func TestServer_jsonRPC(t *testing.T) {
+	s := NewServer(WithJSONRPC("/rpc"))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "call",
+			body: `{"jsonrpc": "2.0", "method": "ghb.test.TestService/GetUser", "params": {"id": "123"}, "id": 1}`,
+			want: `{"jsonrpc": "2.0", "result": {"id": "123", "name": "John Doe", "age": 30}, "id": 1}`,
+		},
+		{
+			name: "Without params",
+			body: `{"jsonrpc": "2.0", "method": "ghb.test.TestService/Ping", "id": "a"}`,
+			want: `{"jsonrpc": "2.0", "result": {}, "id": "a"}`,
+		},
+		{
+			name: "Method error",
+			body: `{"jsonrpc": "2.0", "method": "ghb.test.TestService/GetUser", "params": {"id": "missing"}, "id": 2}`,
+			want: `{"jsonrpc": "2.0", "error": {"code": 5, "message": "user not found", "data": {"code": 5, "message": "user not found", "details": []}}, "id": 2}`,
+		},
+		{
+			name: "Unknown method",
+			body: `{"jsonrpc": "2.0", "method": "ghb.test.TestService/Missing", "id": 3}`,
+			want: `{"jsonrpc": "2.0", "error": {"code": -32601, "message": "unknown method Missing for service ghb.test.TestService"}, "id": 3}`,
+		},
+		{
+			name: "Streaming method",
+			body: `{"jsonrpc": "2.0", "method": "ghb.test.TestService/Chat", "id": 4}`,
+			want: `{"jsonrpc": "2.0", "error": {"code": -32601, "message": "streaming method ghb.test.TestService/Chat is not supported"}, "id": 4}`,
+		},
+		{
+			name: "Invalid params",
+			body: `{"jsonrpc": "2.0", "method": "ghb.test.TestService/GetUser", "params": [1], "id": 5}`,
+			want: `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "params must be an object"}, "id": 5}`,
+		},
+		{
+			name: "Invalid request",
+			body: `{"jsonrpc": "1.0", "method": "ghb.test.TestService/Ping", "id": 6}`,
+			want: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "invalid request"}, "id": null}`,
+		},
+		{
+			name: "Parse error",
+			body: `{"jsonrpc": "2.0", "method"`,
+			want: `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "unexpected end of JSON input"}, "id": null}`,
+		},
+		{
+			name: "Empty batch",
+			body: `[]`,
+			want: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "empty batch"}, "id": null}`,
+		},
+		{
+			name: "Batch",
+			body: `[
+				{"jsonrpc": "2.0", "method": "ghb.test.TestService/GetUser", "params": {"id": "1"}, "id": 1},
+				{"jsonrpc": "2.0", "method": "ghb.test.TestService/Ping"},
+				1,
+				{"jsonrpc": "2.0", "method": "ghb.test.TestService/CreateUser", "params": {"name": "Jane"}, "id": 2}
+			]`,
+			want: `[
+				{"jsonrpc": "2.0", "result": {"id": "1", "name": "John Doe", "age": 30}, "id": 1},
+				{"jsonrpc": "2.0", "error": {"code": -32600, "message": "invalid request"}, "id": null},
+				{"jsonrpc": "2.0", "result": {"id": "", "name": "Jane", "age": 0}, "id": 2}
+			]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := postJSONRPC(t, addr, tt.body)
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			require.Equal(t, contentTypeJSON, res.Header.Get("Content-Type"))
+			require.JSONEq(t, tt.want, body)
+		})
+	}
+
+	t.Run("Notifications", func(t *testing.T) {
+		res, body := postJSONRPC(t, addr, `[{"jsonrpc": "2.0", "method": "ghb.test.TestService/Ping"}]`)
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		require.Empty(t, body)
+	})
+}
+
+func TestServer_jsonRPCNilResult(t *testing.T) {
+	// the result of a nil message is still sent, as an empty one.
+	nilResult := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return (*emptypb.Empty)(nil), nil
+	}
+	s := NewServer(WithJSONRPC("/rpc"), WithUnaryInterceptors(nilResult))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	_, body := postJSONRPC(t, addr, `{"jsonrpc": "2.0", "method": "ghb.test.TestService/Ping", "id": 1}`)
+	require.JSONEq(t, `{"jsonrpc": "2.0", "result": {}, "id": 1}`, body)
+}
@@ -0,0 +1,145 @@
+package ghb
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/malayanand/ghb/api"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// decodeLimits bounds the shape of a decoded request, zero means unlimited.
+type decodeLimits struct {
+	maxDepth      int
+	maxListLength int
+	maxMapSize    int
+}
+
+// checkValue walks the generic value decoded from a request before it is
+// unmarshaled into a message, so the recursion of unmarshalMessage is
+// bounded as well.
+func (l decodeLimits) checkValue(value any) error {
+	return l.checkValueAt(value, 1)
+}
+
+func (l decodeLimits) checkValueAt(value any, depth int) error {
+	switch v := value.(type) {
+	case map[string]any:
+		if err := l.checkDepth(depth); err != nil {
+			return err
+		}
+		if err := l.checkMapSize(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := l.checkValueAt(item, depth+1); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if err := l.checkDepth(depth); err != nil {
+			return err
+		}
+		if err := l.checkListLength(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := l.checkValueAt(item, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkMessage walks a message decoded straight from the wire, e.g. binary
+// protobuf, whose depth is already bounded while unmarshaling.
+func (l decodeLimits) checkMessage(msg protoreflect.Message) error {
+	var err error
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			list := v.List()
+			if err = l.checkListLength(list.Len()); err != nil {
+				return false
+			}
+			if fd.Kind() != protoreflect.MessageKind {
+				return true
+			}
+			for i := 0; i < list.Len(); i++ {
+				if err = l.checkMessage(list.Get(i).Message()); err != nil {
+					return false
+				}
+			}
+		case fd.IsMap():
+			mp := v.Map()
+			if err = l.checkMapSize(mp.Len()); err != nil {
+				return false
+			}
+			if fd.MapValue().Kind() != protoreflect.MessageKind {
+				return true
+			}
+			mp.Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+				err = l.checkMessage(v.Message())
+				return err == nil
+			})
+			return err == nil
+		case fd.Kind() == protoreflect.MessageKind:
+			err = l.checkMessage(v.Message())
+			return err == nil
+		}
+		return true
+	})
+	return err
+}
+
+func (l decodeLimits) checkDepth(depth int) error {
+	if l.maxDepth > 0 && depth > l.maxDepth {
+		return fmt.Errorf("exceeds the maximum nesting depth of %d", l.maxDepth)
+	}
+	return nil
+}
+
+func (l decodeLimits) checkListLength(n int) error {
+	if l.maxListLength > 0 && n > l.maxListLength {
+		return fmt.Errorf("list of %d items exceeds the maximum length of %d", n, l.maxListLength)
+	}
+	return nil
+}
+
+func (l decodeLimits) checkMapSize(n int) error {
+	if l.maxMapSize > 0 && n > l.maxMapSize {
+		return fmt.Errorf("map of %d entries exceeds the maximum size of %d", n, l.maxMapSize)
+	}
+	return nil
+}
+
+// maxMessageSize returns the maximum size of a single message of a stream,
+// which is the maximum body size of the server, or its default if the body
+// size is unlimited, so that the length of a frame can not make the server
+// allocate any amount of memory.
+func (s *Server) maxMessageSize() int64 {
+	if s.maxBodySize > 0 {
+		return s.maxBodySize
+	}
+	return defaultMaxBodySize
+}
+
+// limitBody caps the request body at the max_body_size of the rule, or else
+// at the maximum body size of the server. Bodies known to be too large are
+// rejected right away, in which case false is returned.
+func (s *Server) limitBody(w http.ResponseWriter, r *http.Request, httpRule *api.HttpRule) bool {
+	limit := s.maxBodySize
+	if httpRule.GetMaxBodySize() > 0 {
+		limit = httpRule.GetMaxBodySize()
+	}
+	if limit <= 0 {
+		return true
+	}
+	if r.ContentLength > limit {
+		writeError(w, errBodyTooLarge)
+		return false
+	}
+	r.Body = &maxBytesReader{ReadCloser: r.Body, n: limit}
+	return true
+}
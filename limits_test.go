@@ -0,0 +1,82 @@
+package ghb
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_decodeLimits_checkValue(t *testing.T) {
+	limits := decodeLimits{maxDepth: 3, maxListLength: 2, maxMapSize: 2}
+	tests := []struct {
+		name  string
+		value any
+		isErr bool
+	}{
+		{
+			name:  "within limits",
+			value: map[string]any{"a": map[string]any{"b": []any{"c", "d"}}},
+		},
+		{
+			name:  "too deep",
+			value: map[string]any{"a": map[string]any{"b": []any{map[string]any{}}}},
+			isErr: true,
+		},
+		{
+			name:  "list too long",
+			value: map[string]any{"a": []any{1, 2, 3}},
+			isErr: true,
+		},
+		{
+			name:  "map too large",
+			value: map[string]any{"a": 1, "b": 2, "c": 3},
+			isErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := limits.checkValue(tt.value)
+			if tt.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func Test_decodeLimits_checkMessage(t *testing.T) {
+	limits := decodeLimits{maxListLength: 2, maxMapSize: 1}
+	require.NoError(t, limits.checkMessage((&test.Profile{Tags: []string{"a", "b"}}).ProtoReflect()))
+	require.Error(t, limits.checkMessage((&test.Profile{Tags: []string{"a", "b", "c"}}).ProtoReflect()))
+	require.Error(t, limits.checkMessage((&test.Profile{Labels: map[string]string{"a": "1", "b": "2"}}).ProtoReflect()))
+}
+
+func TestServer_bodyLimits(t *testing.T) {
+	s := NewServer(WithMaxBodySize(64), WithMaxListLength(2))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	post := func(t *testing.T, path, contentType string, body io.Reader) int {
+		res, err := http.Post("http://"+addr+path, contentType, body)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		io.Copy(io.Discard, res.Body)
+		return res.StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, post(t, "/v1/users", contentTypeJSON, strings.NewReader(`{"id": "1"}`)))
+	long := `{"id": "1", "name": "` + strings.Repeat("a", 64) + `"}`
+	require.Equal(t, http.StatusRequestEntityTooLarge, post(t, "/v1/users", contentTypeJSON, strings.NewReader(long)))
+	// without a Content-Length the limit is enforced while reading.
+	require.Equal(t, http.StatusRequestEntityTooLarge, post(t, "/v1/users", contentTypeJSON, io.MultiReader(strings.NewReader(long))))
+	// the rule of the route lowers the limit to 16 bytes.
+	require.Equal(t, http.StatusOK, post(t, "/v1/profiles/1/avatar", "image/png", bytes.NewReader(make([]byte, 16))))
+	require.Equal(t, http.StatusRequestEntityTooLarge, post(t, "/v1/profiles/1/avatar", "image/png", bytes.NewReader(make([]byte, 17))))
+	require.Equal(t, http.StatusBadRequest, post(t, "/v1/profiles/1", contentTypeJSON, strings.NewReader(`{"tags": ["a", "b", "c"]}`)))
+}
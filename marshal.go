@@ -1,7 +1,6 @@
 package ghb
 
 import (
-	"encoding/json"
 	"fmt"
 	"reflect"
 
@@ -14,16 +13,20 @@ type Marshaler interface {
 }
 
 func marshalBytes(msg any) ([]byte, error) {
+	return marshalBytesWith(jsonCodec{}, msg)
+}
+
+func marshalBytesWith(c Codec, msg any) ([]byte, error) {
 	protoMsg, ok := msg.(proto.Message)
 	if !ok {
-		return nil, fmt.Errorf("wrong type %T, expected proto message", protoMsg)
+		return nil, fmt.Errorf("wrong type %T, expected proto message", msg)
 	}
 	response, err := marshalMessage(protoMsg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal response body: %v", msg)
+		return nil, fmt.Errorf("failed to marshal response body: %v", err)
 	}
 
-	return json.Marshal(response)
+	return c.Marshal(response)
 }
 
 func marshalMessage(msg proto.Message) (any, error) {
@@ -38,8 +41,8 @@ func marshalMessage(msg proto.Message) (any, error) {
 		return marshaledMessage, nil
 	}
 
-	protoKeys, err := jsonToProtoKeys(msg)
-	if err != nil {
+	// validates that every field is mapped to a single key.
+	if _, err := jsonToProtoKeys(msg); err != nil {
 		return nil, err
 	}
 	response := make(map[string]any)
@@ -48,10 +51,7 @@ func marshalMessage(msg proto.Message) (any, error) {
 
 	for i := 0; i < fields.Len(); i++ {
 		fd := fields.Get(i)
-		name, ok := protoKeys[string(fd.Name())]
-		if !ok {
-			return nil, fmt.Errorf("key not found %v", fd.Name())
-		}
+		name := jsonKey(fd)
 		if fd.IsMap() {
 			mapValue, err := marshalMap(fd, reflectedMessage)
 			if err != nil {
@@ -0,0 +1,56 @@
+package ghb
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// incomingMetadata exposes the request headers to the handlers as grpc
+// metadata, binary headers are base64 decoded like grpc does on the wire.
+func incomingMetadata(r *http.Request) metadata.MD {
+	return metadataFromHeader(r.Header)
+}
+
+// metadataFromHeader converts http headers into grpc metadata.
+func metadataFromHeader(header http.Header) metadata.MD {
+	md := make(metadata.MD, len(header))
+	for key, values := range header {
+		key = strings.ToLower(key)
+		if !strings.HasSuffix(key, "-bin") {
+			md.Append(key, values...)
+			continue
+		}
+		for _, v := range values {
+			decoded, err := decodeBinaryHeader(v)
+			if err != nil {
+				continue
+			}
+			md.Append(key, string(decoded))
+		}
+	}
+	return md
+}
+
+// headerFromMetadata converts the metadata set by a handler into http headers.
+func headerFromMetadata(md metadata.MD) http.Header {
+	header := make(http.Header, len(md))
+	for key, values := range md {
+		for _, v := range values {
+			if strings.HasSuffix(key, "-bin") {
+				v = base64.RawStdEncoding.EncodeToString([]byte(v))
+			}
+			header.Add(key, v)
+		}
+	}
+	return header
+}
+
+func decodeBinaryHeader(v string) ([]byte, error) {
+	if len(v)%4 == 0 {
+		return base64.StdEncoding.DecodeString(v)
+	}
+	return base64.RawStdEncoding.DecodeString(v)
+}
@@ -0,0 +1,118 @@
+package ghb
+
+import (
+	"context"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Mux serves the grpc requests with a grpc.Server and every other request
+// with a ghb Server, so both are served on the same port.
+type Mux struct {
+	grpcServer *grpc.Server
+	httpServer *Server
+
+	mu       sync.Mutex
+	stopping bool
+	// calls are the grpc calls in flight, waited for by Shutdown.
+	calls sync.WaitGroup
+}
+
+// NewMux returns a mux dispatching to grpcServer and httpServer.
+func NewMux(grpcServer *grpc.Server, httpServer *Server) *Mux {
+	return &Mux{grpcServer: grpcServer, httpServer: httpServer}
+}
+
+// RegisterService registers the service with both servers.
+func (m *Mux) RegisterService(serviceDesc *grpc.ServiceDesc, impl any) {
+	MultiRegistrar(m.grpcServer, m.httpServer).RegisterService(serviceDesc, impl)
+}
+
+// Serve accepts the connections of lis, cleartext HTTP/2 connections (h2c)
+// included as grpc clients use them without TLS.
+func (m *Mux) Serve(lis net.Listener) error {
+	if err := m.httpServer.registerProtosOnce(); err != nil {
+		return err
+	}
+	return m.httpServer.serve(lis, h2c.NewHandler(m, &http2.Server{}))
+}
+
+// Shutdown gracefully stops the mux, as Server.Shutdown does, and then the
+// grpc server. The grpc calls in flight are waited for until ctx expires,
+// after which the grpc server is stopped with Stop, cancelling the calls left.
+// GracefulStop is not used as it does not support the calls of ServeHTTP.
+func (m *Mux) Shutdown(ctx context.Context) error {
+	err := m.httpServer.Shutdown(ctx)
+	m.mu.Lock()
+	m.stopping = true
+	m.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		m.calls.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	m.grpcServer.Stop()
+	return err
+}
+
+// ServeHTTP sends HTTP/2 requests with a Content-Type of application/grpc to
+// the grpc server, and the others to the ghb server. It can be used with an
+// http.Server serving TLS, which negotiates HTTP/2 with the clients.
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isGRPCRequest(r) {
+		m.serveGRPC(w, r)
+		return
+	}
+	if err := m.httpServer.registerProtosOnce(); err != nil {
+		internalServerError(w, err)
+		return
+	}
+	m.httpServer.ServeHTTP(w, r)
+}
+
+// serveGRPC serves a grpc call with the grpc server, unless the mux is being
+// shut down, in which case the call fails with Unavailable.
+func (m *Mux) serveGRPC(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	if m.stopping {
+		m.mu.Unlock()
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", strconv.Itoa(int(codes.Unavailable)))
+		w.Header().Set("Grpc-Message", "the server is shutting down")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	m.calls.Add(1)
+	m.mu.Unlock()
+	defer m.calls.Done()
+	m.grpcServer.ServeHTTP(w, r)
+}
+
+// isGRPCRequest reports whether r is a request of the grpc protocol, whose
+// content type is application/grpc or application/grpc+codec.
+func isGRPCRequest(r *http.Request) bool {
+	if r.ProtoMajor != 2 {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/grpc" || strings.HasPrefix(mediaType, "application/grpc+")
+}
@@ -0,0 +1,133 @@
+package ghb
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestMux(t *testing.T) {
+	m := NewMux(grpc.NewServer(), NewServer())
+	test.RegisterTestServiceServer(m, testService{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go m.Serve(lis)
+	t.Cleanup(func() { lis.Close() })
+	addr := lis.Addr().String()
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := test.NewTestServiceClient(conn)
+	user, err := client.GetUser(context.Background(), &test.GetUserRequest{Id: "123"})
+	require.NoError(t, err)
+	require.Equal(t, "John Doe", user.Name)
+	_, err = client.GetUser(context.Background(), &test.GetUserRequest{Id: "missing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	res, err := http.Get("http://" + addr + "/v1/users/123")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.JSONEq(t, `{"id": "123", "name": "John Doe", "age": 30}`, string(body))
+
+	chat, _ := dialTestWS(t, addr, "/v1/rooms/general/chat", nil)
+	chat.write(t, wsOpText, []byte(`{"text": "hi"}`))
+	_, payload := chat.read(t)
+	require.JSONEq(t, `{"room": "general", "text": "echo: hi"}`, string(payload))
+}
+
+// newTestMux serves the test service with a mux and returns a grpc client
+// connected to it.
+func newTestMux(t *testing.T) (*Mux, test.TestServiceClient) {
+	t.Helper()
+	m := NewMux(grpc.NewServer(), NewServer())
+	test.RegisterTestServiceServer(m, testService{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go m.Serve(lis)
+	t.Cleanup(func() { lis.Close() })
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return m, test.NewTestServiceClient(conn)
+}
+
+func TestMux_Shutdown(t *testing.T) {
+	t.Run("waits for grpc calls", func(t *testing.T) {
+		m, client := newTestMux(t)
+		chat, err := client.Chat(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, chat.Send(&test.ChatMessage{Text: "hi"}))
+		_, err = chat.Recv()
+		require.NoError(t, err)
+
+		shutdown := make(chan error, 1)
+		go func() { shutdown <- m.Shutdown(context.Background()) }()
+		select {
+		case err := <-shutdown:
+			t.Fatalf("shutdown returned before the call ended: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		require.NoError(t, chat.CloseSend())
+		_, err = chat.Recv()
+		require.ErrorIs(t, err, io.EOF)
+		require.NoError(t, <-shutdown)
+	})
+
+	t.Run("stops grpc calls once the context expires", func(t *testing.T) {
+		m, client := newTestMux(t)
+		chat, err := client.Chat(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, chat.Send(&test.ChatMessage{Text: "hi"}))
+		_, err = chat.Recv()
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, m.Shutdown(ctx), context.DeadlineExceeded)
+		_, err = chat.Recv()
+		require.Error(t, err)
+		require.NotErrorIs(t, err, io.EOF)
+	})
+}
+
+func Test_isGRPCRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		protoMajor  int
+		contentType string
+		want        bool
+	}{
+		{name: "grpc", protoMajor: 2, contentType: "application/grpc", want: true},
+		{name: "grpc with codec", protoMajor: 2, contentType: "application/grpc+proto", want: true},
+		{name: "grpc over HTTP/1", protoMajor: 1, contentType: "application/grpc"},
+		{name: "grpc-web", protoMajor: 2, contentType: "application/grpc-web"},
+		{name: "json", protoMajor: 2, contentType: "application/json"},
+		{name: "no content type", protoMajor: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/", nil)
+			require.NoError(t, err)
+			r.ProtoMajor = tt.protoMajor
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			require.Equal(t, tt.want, isGRPCRequest(r))
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package ghb
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/malayanand/ghb/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const openAPIVersion = "3.1.0"
+
+// OpenAPIInfo is the info object of the generated OpenAPI document, the
+// title defaults to the names of the registered services and the version to
+// 0.0.0.
+type OpenAPIInfo struct {
+	Title       string
+	Description string
+	Version     string
+}
+
+// registeredRule is a method registered for an http rule.
+type registeredRule struct {
+	method protoreflect.MethodDescriptor
+	rule   *api.HttpRule
+}
+
+// OpenAPI returns an OpenAPI 3.1 document describing the http rules of the
+// registered services. Streaming methods are left out, as websockets can not
+// be described by OpenAPI.
+func (s *Server) OpenAPI() ([]byte, error) {
+	if err := s.registerProtosOnce(); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(s.openAPIDocument(), "", "  ")
+}
+
+func (s *Server) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc, err := s.OpenAPI()
+	if err != nil {
+		internalServerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.Write(doc)
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]map[string]any `json:"schemas"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      map[string]any `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema map[string]any `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Headers     map[string]openAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIHeader struct {
+	Description string         `json:"description,omitempty"`
+	Schema      map[string]any `json:"schema"`
+}
+
+// openAPIGenerator collects the schemas of the messages referenced by the
+// operations of a document.
+type openAPIGenerator struct {
+	schemas map[string]map[string]any
+}
+
+func (s *Server) openAPIDocument() *openAPIDocument {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	g := &openAPIGenerator{schemas: make(map[string]map[string]any)}
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:       s.openAPIInfo.Title,
+			Description: s.openAPIInfo.Description,
+			Version:     s.openAPIInfo.Version,
+		},
+		Paths: make(map[string]map[string]*openAPIOperation),
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = strings.Join(slices.Sorted(maps.Keys(s.services)), ", ")
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "0.0.0"
+	}
+	for _, r := range s.rules {
+		if r.method.IsStreamingClient() || r.method.IsStreamingServer() {
+			continue
+		}
+		p := openAPIPath(r.rule.Path)
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = make(map[string]*openAPIOperation)
+		}
+		doc.Paths[p][strings.ToLower(r.rule.Method.String())] = g.operation(r.method, r.rule)
+	}
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+// openAPIPath turns the path of a rule into an OpenAPI path template, which
+// has no wildcards matching the rest of the path.
+func openAPIPath(rulePath string) string {
+	segments := strings.Split(strings.Trim(rulePath, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = strings.Replace(segment, "...}", "}", 1)
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+func (g *openAPIGenerator) operation(method protoreflect.MethodDescriptor, httpRule *api.HttpRule) *openAPIOperation {
+	input := method.Input()
+	op := &openAPIOperation{
+		OperationID: string(method.Parent().Name()) + "_" + string(method.Name()),
+		Description: comments(method),
+		Tags:        []string{string(method.Parent().FullName())},
+		Parameters:  g.parameters(input, httpRule.Path),
+		Responses:   map[string]*openAPIResponse{},
+	}
+	switch {
+	case hasHttpBody(input):
+		op.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  map[string]openAPIMediaType{"*/*": {Schema: binarySchema()}},
+		}
+	case httpRule.Method != api.HttpRule_HttpMethod_GET && httpRule.Method != api.HttpRule_HttpMethod_HEAD && httpRule.Method != api.HttpRule_HttpMethod_DELETE:
+		op.RequestBody = &openAPIRequestBody{
+			Content: map[string]openAPIMediaType{contentTypeJSON: {Schema: g.messageRef(input)}},
+		}
+	}
+	op.Responses[strconv.Itoa(successCode(httpRule))] = g.successResponse(method.Output(), httpRule)
+	op.Responses["default"] = &openAPIResponse{
+		Description: "Error",
+		Content: map[string]openAPIMediaType{
+			contentTypeJSON: {Schema: g.messageRef(status.New(codes.Unknown, "").Proto().ProtoReflect().Descriptor())},
+		},
+	}
+	return op
+}
+
+// parameters returns the path params of the rule, the fields of the input
+// bound to headers and cookies and the other fields which can be set by query
+// params, see queryKeys.
+func (g *openAPIGenerator) parameters(input protoreflect.MessageDescriptor, rulePath string) []openAPIParameter {
+	keys, _ := descriptorKeys(input)
+	var params []openAPIParameter
+	inPath := make(map[string]bool)
+	for _, segment := range strings.Split(strings.Trim(rulePath, "/"), "/") {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name := strings.TrimSuffix(segment[1:len(segment)-1], "...")
+		inPath[name] = true
+		param := openAPIParameter{Name: name, In: "path", Required: true, Schema: map[string]any{"type": "string"}}
+		if fd := input.Fields().ByName(protoreflect.Name(keys[name])); fd != nil {
+			param.Description = comments(fd)
+			param.Schema = g.fieldSchema(fd)
+		}
+		params = append(params, param)
+	}
+	fields := input.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		rule := proto.GetExtension(fd.Options(), api.E_Field).(*api.FieldRule)
+		if rule.GetHeader() != "" {
+			params = append(params, openAPIParameter{Name: rule.GetHeader(), In: "header", Description: comments(fd), Schema: g.fieldSchema(fd)})
+		}
+		if rule.GetCookie() != "" {
+			params = append(params, openAPIParameter{Name: rule.GetCookie(), In: "cookie", Description: comments(fd), Schema: g.fieldSchema(fd)})
+		}
+	}
+	query := queryKeys(input)
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		rule := proto.GetExtension(fd.Options(), api.E_Field).(*api.FieldRule)
+		name := jsonKey(fd)
+		if !query[name] || inPath[name] || rule.GetHeader() != "" || rule.GetCookie() != "" {
+			continue
+		}
+		params = append(params, openAPIParameter{Name: name, In: "query", Description: comments(fd), Schema: g.fieldSchema(fd)})
+	}
+	return params
+}
+
+func (g *openAPIGenerator) successResponse(output protoreflect.MessageDescriptor, httpRule *api.HttpRule) *openAPIResponse {
+	res := &openAPIResponse{Description: http.StatusText(successCode(httpRule))}
+	if res.Description == "" {
+		res.Description = "Success"
+	}
+	headers := make(map[string]openAPIHeader)
+	if httpRule.GetLocation() != "" {
+		headers["Location"] = openAPIHeader{Schema: map[string]any{"type": "string"}}
+	}
+	fields := output.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		rule := proto.GetExtension(fd.Options(), api.E_Field).(*api.FieldRule)
+		if rule.GetResponseHeader() != "" {
+			headers[rule.GetResponseHeader()] = openAPIHeader{Description: comments(fd), Schema: g.fieldSchema(fd)}
+		}
+	}
+	if len(headers) > 0 {
+		res.Headers = headers
+	}
+	switch {
+	case isEmpty(output) || !bodyAllowed(successCode(httpRule)):
+	case hasHttpBody(output):
+		res.Content = map[string]openAPIMediaType{"*/*": {Schema: binarySchema()}}
+	default:
+		res.Content = map[string]openAPIMediaType{contentTypeJSON: {Schema: g.messageRef(output)}}
+	}
+	return res
+}
+
+// messageRef returns a reference to the schema of the message, adding it to
+// the components of the document if it is not there yet.
+func (g *openAPIGenerator) messageRef(md protoreflect.MessageDescriptor) map[string]any {
+	name := string(md.FullName())
+	ref := map[string]any{"$ref": "#/components/schemas/" + name}
+	if _, ok := g.schemas[name]; ok {
+		return ref
+	}
+	schema := map[string]any{"type": "object"}
+	// added before the fields so recursive messages refer to it.
+	g.schemas[name] = schema
+	if description := comments(md); description != "" {
+		schema["description"] = description
+	}
+	properties := make(map[string]any)
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		property := g.fieldSchema(fd)
+		if description := comments(fd); description != "" {
+			property = withKey(property, "description", description)
+		}
+		properties[jsonKey(fd)] = property
+	}
+	schema["properties"] = properties
+	return ref
+}
+
+func (g *openAPIGenerator) fieldSchema(fd protoreflect.FieldDescriptor) map[string]any {
+	switch {
+	case fd.IsMap():
+		return map[string]any{"type": "object", "additionalProperties": g.singularSchema(fd.MapValue())}
+	case fd.IsList():
+		return map[string]any{"type": "array", "items": g.singularSchema(fd)}
+	}
+	return g.singularSchema(fd)
+}
+
+// singularSchema returns the schema of a single value of the field, as
+// written by marshalMessage.
+func (g *openAPIGenerator) singularSchema(fd protoreflect.FieldDescriptor) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "uint32", "minimum": 0}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "integer", "format": "uint64", "minimum": 0}
+	case protoreflect.FloatKind:
+		return map[string]any{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]any{"type": "number", "format": "double"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		numbers := make([]int32, values.Len())
+		for i := range numbers {
+			numbers[i] = int32(values.Get(i).Number())
+		}
+		return map[string]any{"type": "integer", "format": "int32", "enum": numbers}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.messageRef(fd.Message())
+	}
+	return map[string]any{}
+}
+
+func binarySchema() map[string]any {
+	return map[string]any{"type": "string", "format": "binary"}
+}
+
+// withKey returns a copy of the schema with the key set, leaving schemas
+// shared between fields as they are.
+func withKey(schema map[string]any, key string, value any) map[string]any {
+	copied := make(map[string]any, len(schema)+1)
+	for k, v := range schema {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+// comments returns the leading comments of the descriptor in its source file,
+// which are only available if the descriptor was built with source info.
+func comments(d protoreflect.Descriptor) string {
+	return strings.TrimSpace(d.ParentFile().SourceLocations().ByDescriptor(d).LeadingComments)
+}
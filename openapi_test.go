@@ -0,0 +1,117 @@
+package ghb
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_OpenAPI(t *testing.T) {
+	s := NewServer()
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	data, err := s.OpenAPI()
+	require.NoError(t, err)
+
+	var doc struct {
+		OpenAPI string                                      `json:"openapi"`
+		Info    map[string]string                           `json:"info"`
+		Paths   map[string]map[string]map[string]any        `json:"paths"`
+		Comps   struct{ Schemas map[string]map[string]any } `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	require.Equal(t, "3.1.0", doc.OpenAPI)
+	require.Equal(t, map[string]string{"title": "ghb.test.TestService", "version": "0.0.0"}, doc.Info)
+
+	// streaming methods are left out.
+	require.NotContains(t, doc.Paths, "/v1/rooms/{room}/chat")
+	require.Contains(t, doc.Paths["/v1/users/{id}"], "get")
+	require.Contains(t, doc.Paths["/v1/users/{id}"], "delete")
+
+	get, err := json.Marshal(doc.Paths["/v1/users/{id}"]["get"]["parameters"])
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}},
+		{"name": "X-Tenant-Id", "in": "header", "schema": {"type": "string"}},
+		{"name": "session", "in": "cookie", "schema": {"type": "string"}}
+	]`, string(get))
+
+	invite, err := json.Marshal(doc.Paths["/v1/invites"]["post"]["responses"])
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"201": {
+			"description": "Created",
+			"headers": {"Location": {"schema": {"type": "string"}}},
+			"content": {"application/json": {"schema": {"$ref": "#/components/schemas/ghb.test.TestUser"}}}
+		},
+		"default": {
+			"description": "Error",
+			"content": {"application/json": {"schema": {"$ref": "#/components/schemas/google.rpc.Status"}}}
+		}
+	}`, string(invite))
+	require.NotContains(t, doc.Paths["/v1/users/{id}"]["delete"]["responses"].(map[string]any)["204"], "content")
+
+	profile, err := json.Marshal(doc.Comps.Schemas["ghb.test.Profile"])
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"id": {"type": "string"},
+			"displayName": {"type": "string"},
+			"avatar": {"type": "string", "contentEncoding": "base64"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"address": {"$ref": "#/components/schemas/ghb.test.Address"},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}}
+		}
+	}`, string(profile))
+	require.Contains(t, doc.Comps.Schemas["ghb.test.Address"]["properties"], "zipCode")
+}
+
+func TestServer_OpenAPIComments(t *testing.T) {
+	files, err := LoadFileDescriptorSet("testdata/greeter.binpb")
+	require.NoError(t, err)
+	s := NewServer()
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	s.RegisterProxyFiles(files, nil)
+	data, err := s.OpenAPI()
+	require.NoError(t, err)
+
+	var doc struct {
+		Paths map[string]map[string]map[string]any        `json:"paths"`
+		Comps struct{ Schemas map[string]map[string]any } `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	greet := doc.Paths["/v1/greet/{name}"]["get"]
+	require.Equal(t, "Greet answers with a greeting for the name.", greet["description"])
+	params, err := json.Marshal(greet["parameters"])
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"name": "name", "in": "path", "required": true, "description": "The name of the person to greet.", "schema": {"type": "string"}},
+		{"name": "greeting", "in": "query", "description": "The greeting to use, defaults to hello.", "schema": {"type": "string"}}
+	]`, string(params))
+	require.Equal(t, "GreetReply holds the greeting.", doc.Comps.Schemas["ghb.greeter.GreetReply"]["description"])
+
+	// the descriptors generated by protoc-gen-go have no source info.
+	require.NotContains(t, doc.Paths["/v1/users/{id}"]["get"], "description")
+}
+
+func TestServer_serveOpenAPI(t *testing.T) {
+	s := NewServer(WithOpenAPI("/openapi.json", OpenAPIInfo{Title: "Test", Version: "1.0.0"}))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	res, err := http.Get("http://" + addr + "/openapi.json")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(body, &doc))
+	require.Equal(t, map[string]any{"title": "Test", "version": "1.0.0"}, doc["info"])
+}
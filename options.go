@@ -0,0 +1,171 @@
+package ghb
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	defaultMaxFormPartSize      = 10 << 20
+	defaultCompressionThreshold = 1024
+	defaultMaxDecompressedSize  = 32 << 20
+	// same as the default maximum receive message size of a grpc server.
+	defaultMaxBodySize      = 4 << 20
+	defaultMaxDecodeDepth   = 100
+	defaultMaxBatchRequests = 100
+	defaultMaxBatchResponse = 4 << 20
+	defaultMaxBatchTotal    = 16 << 20
+)
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithMaxFormPartSize sets the maximum size in bytes of a single part of a
+// multipart/form-data request body, larger parts fail the request. Defaults
+// to 10MiB.
+func WithMaxFormPartSize(n int64) ServerOption {
+	return func(s *Server) {
+		s.maxFormPartSize = n
+	}
+}
+
+// WithCompressionThreshold sets the minimum size in bytes of a response body
+// for it to be compressed. Defaults to 1KiB.
+func WithCompressionThreshold(n int) ServerOption {
+	return func(s *Server) {
+		s.compressionThreshold = n
+	}
+}
+
+// WithMaxDecompressedSize sets the maximum size in bytes a compressed request
+// body may decompress to, larger bodies fail the request. Defaults to 32MiB.
+func WithMaxDecompressedSize(n int64) ServerOption {
+	return func(s *Server) {
+		s.maxDecompressedSize = n
+	}
+}
+
+// WithMaxBodySize sets the maximum size in bytes of a request body, larger
+// bodies are rejected with 413 Request Entity Too Large. The max_body_size of
+// an http rule overrides it for its route. Defaults to 4MiB, zero means
+// unlimited, though the messages of gRPC-Web and Connect streams are still
+// limited to 4MiB each.
+func WithMaxBodySize(n int64) ServerOption {
+	return func(s *Server) {
+		s.maxBodySize = n
+	}
+}
+
+// WithMaxDecodeDepth sets how deeply messages, lists and maps may be nested
+// in a request. Defaults to 100, zero means unlimited.
+func WithMaxDecodeDepth(n int) ServerOption {
+	return func(s *Server) {
+		s.limits.maxDepth = n
+	}
+}
+
+// WithMaxListLength sets the maximum number of items of a repeated field in
+// a request. Defaults to unlimited.
+func WithMaxListLength(n int) ServerOption {
+	return func(s *Server) {
+		s.limits.maxListLength = n
+	}
+}
+
+// WithMaxMapSize sets the maximum number of entries of a map field in a
+// request. Defaults to unlimited.
+func WithMaxMapSize(n int) ServerOption {
+	return func(s *Server) {
+		s.limits.maxMapSize = n
+	}
+}
+
+// WithCORS answers cross-origin requests according to the policy, including
+// the OPTIONS preflights for every path of the registered rules.
+func WithCORS(policy CORSPolicy) ServerOption {
+	return func(s *Server) {
+		s.cors = &policy
+	}
+}
+
+// WithOpenAPI serves the OpenAPI document of the registered services at the
+// given path.
+func WithOpenAPI(path string, info OpenAPIInfo) ServerOption {
+	return func(s *Server) {
+		s.openAPIPath = path
+		s.openAPIInfo = info
+	}
+}
+
+// WithJSONRPC serves JSON-RPC 2.0 requests for the unary methods of the
+// registered services at the given path, the method of a request being the
+// full name of a method, e.g. "package.Service/Method".
+func WithJSONRPC(path string) ServerOption {
+	return func(s *Server) {
+		s.jsonRPCPath = path
+	}
+}
+
+// WithBatch serves batches of requests for the registered routes at the given
+// path, running up to concurrency of the requests of a batch at once. A
+// concurrency of zero or one runs them one after the other.
+func WithBatch(path string, concurrency int) ServerOption {
+	return func(s *Server) {
+		s.batchPath = path
+		s.batchConcurrency = concurrency
+	}
+}
+
+// WithBatchLimits sets the maximum number of requests of a batch, larger
+// batches fail with 400 Bad Request, the maximum size in bytes of the
+// response of each request, which is buffered until the batch is done, and
+// of the responses of all the requests of a batch. A larger response is
+// replaced with an error, as are the responses of the requests once the
+// total is exceeded. Defaults to 100 requests, 4MiB and 16MiB, zero means
+// unlimited.
+func WithBatchLimits(maxRequests int, maxResponseSize, maxTotalSize int64) ServerOption {
+	return func(s *Server) {
+		s.maxBatchRequests = maxRequests
+		s.maxBatchResponse = maxResponseSize
+		s.maxBatchTotal = maxTotalSize
+	}
+}
+
+// WithHealth serves the /healthz liveness and /readyz readiness probes with
+// the statuses of the health server, e.g. a health.Server. The status of a
+// single service is checked with the service query parameter. Registering
+// the health server as a service also serves its rpc, e.g. with Connect.
+func WithHealth(health grpc_health_v1.HealthServer) ServerOption {
+	return func(s *Server) {
+		s.health = health
+	}
+}
+
+// WithShutdownDelay sets how long Shutdown keeps serving after reporting the
+// server as not ready, for load balancers to stop sending it requests.
+// Defaults to zero.
+func WithShutdownDelay(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.shutdownDelay = d
+	}
+}
+
+// WithUnaryInterceptors adds interceptors around the calls of unary methods,
+// whichever protocol they are served with. The first interceptor is the
+// outermost, like with grpc.ChainUnaryInterceptor.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) ServerOption {
+	return func(s *Server) {
+		s.unaryInterceptors = append(s.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors adds interceptors around the calls of streaming
+// methods, whichever protocol they are served with. The first interceptor is
+// the outermost, like with grpc.ChainStreamInterceptor.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) ServerOption {
+	return func(s *Server) {
+		s.streamInterceptors = append(s.streamInterceptors, interceptors...)
+	}
+}
@@ -0,0 +1,244 @@
+package ghb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// forwardedHeaders are the headers of the http request forwarded as metadata
+// to the backend, along with the headers prefixed with metadataHeaderPrefix.
+// Other headers, such as cookies, are meant for the gateway and are dropped.
+var forwardedHeaders = map[string]bool{
+	"authorization": true,
+	"x-request-id":  true,
+	"traceparent":   true,
+	"tracestate":    true,
+}
+
+// metadataHeaderPrefix marks a request header forwarded as metadata without
+// the prefix, following the convention of grpc-gateway.
+const metadataHeaderPrefix = "grpc-metadata-"
+
+// RegisterProxy serves the http rules of the service by forwarding the calls
+// to a remote grpc server over cc, instead of calling an implementation in
+// process. The Authorization, X-Request-Id and trace context headers and the
+// headers prefixed with Grpc-Metadata- are forwarded as metadata, and a
+// Grpc-Timeout header sets the deadline of the call.
+func (s *Server) RegisterProxy(serviceDesc *grpc.ServiceDesc, cc grpc.ClientConnInterface) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := serviceDesc.ServiceName
+	if err := s.checkRegistration(name, nil, nil); err != nil {
+		s.registrationFailed(err)
+		return
+	}
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		s.registrationFailed(fmt.Errorf("ghb: service %q not found: %w", name, err))
+		return
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		s.registrationFailed(fmt.Errorf("ghb: %q is not a service", name))
+		return
+	}
+	s.registerProxy(sd, cc)
+}
+
+// registerProxy registers a service desc whose handlers forward every method
+// of the service to cc.
+func (s *Server) registerProxy(sd protoreflect.ServiceDescriptor, cc grpc.ClientConnInterface) {
+	serviceDesc := &grpc.ServiceDesc{
+		ServiceName: string(sd.FullName()),
+		HandlerType: (*any)(nil),
+	}
+	methods := sd.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+		if method.IsStreamingClient() || method.IsStreamingServer() {
+			serviceDesc.Streams = append(serviceDesc.Streams, grpc.StreamDesc{
+				StreamName:    string(method.Name()),
+				Handler:       proxyStreamHandler(cc, method),
+				ServerStreams: method.IsStreamingServer(),
+				ClientStreams: method.IsStreamingClient(),
+			})
+			continue
+		}
+		serviceDesc.Methods = append(serviceDesc.Methods, grpc.MethodDesc{
+			MethodName: string(method.Name()),
+			Handler:    proxyMethodHandler(cc, method),
+		})
+	}
+	s.register(serviceDesc, nil)
+	s.services[serviceDesc.ServiceName].desc = sd
+}
+
+func fullMethodName(method protoreflect.MethodDescriptor) string {
+	return fmt.Sprintf("/%s/%s", method.Parent().FullName(), method.Name())
+}
+
+func proxyMethodHandler(cc grpc.ClientConnInterface, method protoreflect.MethodDescriptor) grpc.MethodHandler {
+	fullMethod := fullMethodName(method)
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		in := newMessage(method.Input())
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req any) (any, error) {
+			ctx, cancel, err := proxyContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+			defer cancel()
+			out := newMessage(method.Output())
+			var header, trailer metadata.MD
+			err = cc.Invoke(ctx, fullMethod, req, out, grpc.Header(&header), grpc.Trailer(&trailer))
+			// not every transport supports metadata, which is then dropped.
+			grpc.SetHeader(ctx, header)
+			grpc.SetTrailer(ctx, trailer)
+			if err != nil {
+				return nil, err
+			}
+			return out, nil
+		}
+		if interceptor == nil {
+			return handler(ctx, in)
+		}
+		return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}, handler)
+	}
+}
+
+// proxyStreamHandler pipes the messages of the stream to the remote stream
+// and back, the status of the remote stream ends the stream.
+func proxyStreamHandler(cc grpc.ClientConnInterface, method protoreflect.MethodDescriptor) grpc.StreamHandler {
+	fullMethod := fullMethodName(method)
+	desc := &grpc.StreamDesc{
+		StreamName:    string(method.Name()),
+		ServerStreams: method.IsStreamingServer(),
+		ClientStreams: method.IsStreamingClient(),
+	}
+	return func(srv any, ss grpc.ServerStream) error {
+		ctx, cancel, err := proxyContext(ss.Context())
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		cs, err := cc.NewStream(ctx, desc, fullMethod)
+		if err != nil {
+			return err
+		}
+		go func() {
+			for {
+				in := newMessage(method.Input())
+				if err := ss.RecvMsg(in); err != nil {
+					if err == io.EOF {
+						cs.CloseSend()
+					} else {
+						cancel()
+					}
+					return
+				}
+				if err := cs.SendMsg(in); err != nil {
+					return
+				}
+			}
+		}()
+		for i := 0; ; i++ {
+			out := newMessage(method.Output())
+			err := cs.RecvMsg(out)
+			// the header is there once the first message or the status is,
+			// and has to be set before either is relayed.
+			if i == 0 {
+				if header, err := cs.Header(); err == nil {
+					ss.SetHeader(header)
+				}
+			}
+			if err == io.EOF {
+				ss.SetTrailer(cs.Trailer())
+				return nil
+			}
+			if err != nil {
+				ss.SetTrailer(cs.Trailer())
+				return err
+			}
+			if err := ss.SendMsg(out); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// proxyContext forwards the incoming metadata of ctx as the outgoing metadata
+// of the remote call, with the deadline of the Grpc-Timeout header.
+func proxyContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	incoming, _ := metadata.FromIncomingContext(ctx)
+	outgoing := make(metadata.MD, len(incoming))
+	for key, values := range incoming {
+		switch {
+		case forwardedHeaders[key]:
+			outgoing[key] = values
+		case strings.HasPrefix(key, metadataHeaderPrefix) && len(key) > len(metadataHeaderPrefix):
+			outgoing.Append(strings.TrimPrefix(key, metadataHeaderPrefix), values...)
+		}
+	}
+	ctx = metadata.NewOutgoingContext(ctx, outgoing)
+	timeouts := incoming.Get("grpc-timeout")
+	if len(timeouts) == 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+	timeout, err := parseTimeout(timeouts[0])
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+// parseTimeout parses a timeout in the format of the grpc-timeout header,
+// e.g. "100m" for 100 milliseconds.
+func parseTimeout(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || len(s) > 9 {
+		return 0, fmt.Errorf("invalid grpc-timeout %q", s)
+	}
+	unit, ok := units[s[len(s)-1]]
+	if !ok {
+		return 0, fmt.Errorf("invalid grpc-timeout unit in %q", s)
+	}
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid grpc-timeout %q", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// newMessage returns a new message of the generated type of md, or a dynamic
+// message if the type is not linked into the binary.
+func newMessage(md protoreflect.MessageDescriptor) proto.Message {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(md.FullName()); err == nil {
+		return mt.New().Interface()
+	}
+	return dynamicpb.NewMessage(md)
+}
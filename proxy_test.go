@@ -0,0 +1,148 @@
+package ghb
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// newTestProxy serves the test service with a grpc server, and a ghb server
+// proxying to it. Calls reaching the grpc server are sent on calls.
+func newTestProxy(t *testing.T) (string, chan context.Context) {
+	t.Helper()
+	calls := make(chan context.Context, 1)
+	backend := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		select {
+		case calls <- ctx:
+		default:
+		}
+		return handler(ctx, req)
+	}))
+	test.RegisterTestServiceServer(backend, testService{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go backend.Serve(lis)
+	t.Cleanup(backend.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	s := NewServer()
+	s.RegisterProxy(&test.TestService_ServiceDesc, conn)
+	return serveTest(t, s), calls
+}
+
+func TestServer_proxyUnary(t *testing.T) {
+	addr, calls := newTestProxy(t)
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/v1/users/123", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Request-Id", "r1")
+	req.Header.Set("Grpc-Metadata-X-User", "jane")
+	req.Header.Set("Cookie", "session=s1")
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Grpc-Timeout", "5S")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.JSONEq(t, `{"id": "123", "name": "John Doe", "age": 30}`, string(body))
+	// the header metadata of the backend is sent as headers.
+	require.Equal(t, "directory", res.Header.Get("X-User-Source"))
+
+	ctx := <-calls
+	md, _ := metadata.FromIncomingContext(ctx)
+	require.Equal(t, []string{"r1"}, md.Get("x-request-id"))
+	require.Equal(t, []string{"jane"}, md.Get("x-user"))
+	require.Empty(t, md.Get("grpc-metadata-x-user"))
+	require.Empty(t, md.Get("connection"))
+	require.Empty(t, md.Get("cookie"))
+	require.Empty(t, md.Get("origin"))
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(5*time.Second), deadline, 2*time.Second)
+
+	res, err = http.Get("http://" + addr + "/v1/users/missing")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	req, err = http.NewRequest(http.MethodGet, "http://"+addr+"/v1/users/123", nil)
+	require.NoError(t, err)
+	req.Header.Set("Grpc-Timeout", "soon")
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestServer_proxyStream(t *testing.T) {
+	addr, _ := newTestProxy(t)
+	client, _ := dialTestWS(t, addr, "/v1/rooms/general/chat", nil)
+
+	client.write(t, wsOpText, []byte(`{"text": "hi"}`))
+	opcode, payload := client.read(t)
+	require.Equal(t, byte(wsOpText), opcode)
+	require.JSONEq(t, `{"room": "general", "text": "echo: hi"}`, string(payload))
+
+	client.write(t, wsOpText, []byte(`{"text": "bye"}`))
+	code, reason := client.readClose(t)
+	require.Equal(t, wsCloseStatusCodeOffset+10, code) // codes.Aborted
+	require.Equal(t, "conversation ended", reason)
+}
+
+func TestServer_proxyHeaders(t *testing.T) {
+	addr, _ := newTestProxy(t)
+
+	res, _ := postConnect(t, addr, "/ghb.test.TestService/Ping", contentTypeJSON, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "1", res.Header.Get("X-Pong"))
+
+	// the header of a stream ending without a message is sent as well.
+	header := http.Header{"Grpc-Metadata-X-User": {"jane"}}
+	res, body := postConnect(t, addr, "/ghb.test.TestService/Chat", "application/connect+json", connectEnvelope(0, `{"text": "bye"}`), header)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "hello jane", res.Header.Get("X-Greeting"))
+	messages, end := readEnvelopes(t, body)
+	require.Empty(t, messages)
+	require.JSONEq(t, `{"error": {"code": "aborted", "message": "conversation ended"}}`, end)
+}
+
+func Test_parseTimeout(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "5S", want: 5 * time.Second},
+		{in: "100m", want: 100 * time.Millisecond},
+		{in: "2H", want: 2 * time.Hour},
+		{in: "10", wantErr: true},
+		{in: "S", wantErr: true},
+		{in: "-1S", wantErr: true},
+		{in: "1234567890S", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseTimeout(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package ghb
+
+import (
+	"fmt"
+	"net/http"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// queryKeys returns the keys of the fields of md which can be set by query
+// params, which are the fields of a single scalar value. The clients
+// generated by protoc-gen-ghb send the fields of a request which are in
+// neither the path nor the body as query params, rules without a body would
+// otherwise lose them.
+func queryKeys(md protoreflect.MessageDescriptor) map[string]bool {
+	keys := make(map[string]bool)
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.IsList() || fd.IsMap() || fd.Message() != nil {
+			continue
+		}
+		keys[jsonKey(fd)] = true
+	}
+	return keys
+}
+
+// bindQuery adds the query params of the request for the given keys to the
+// path params, other query params are ignored. A param given twice, or for a
+// field already bound by the path, is ambiguous and fails the request rather
+// than silently picking one of the values.
+func bindQuery(r *http.Request, keys map[string]bool, params map[string]string) error {
+	for key, values := range r.URL.Query() {
+		if !keys[key] {
+			continue
+		}
+		if len(values) > 1 {
+			return fmt.Errorf("query parameter %q is repeated", key)
+		}
+		if existing, ok := params[key]; ok {
+			return fmt.Errorf("parameter conflict: %q already exists with value %v", key, existing)
+		}
+		params[key] = values[0]
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package ghb
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_bindQuery(t *testing.T) {
+	keys := queryKeys((&test.Profile{}).ProtoReflect().Descriptor())
+	require.Equal(t, map[string]bool{"id": true, "displayName": true, "avatar": true}, keys)
+
+	params := map[string]string{"id": "1"}
+	r := httptest.NewRequest(http.MethodGet, "/?displayName=Jane&tags=a&other=x", nil)
+	require.NoError(t, bindQuery(r, keys, params))
+	require.Equal(t, map[string]string{"id": "1", "displayName": "Jane"}, params)
+
+	r = httptest.NewRequest(http.MethodGet, "/?id=2", nil)
+	require.Error(t, bindQuery(r, keys, params))
+	r = httptest.NewRequest(http.MethodGet, "/?avatar=a&avatar=b", nil)
+	require.Error(t, bindQuery(r, keys, map[string]string{}))
+}
+
+func TestServer_queryParams(t *testing.T) {
+	addr := newTestServer(t)
+
+	post := func(t *testing.T, path, body string) (int, string) {
+		res, err := http.Post("http://"+addr+path, contentTypeJSON, strings.NewReader(body))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(data)
+	}
+
+	code, body := post(t, "/v1/profiles/1?displayName=Jane&tags=a&other=x", `{}`)
+	require.Equal(t, http.StatusOK, code)
+	require.JSONEq(t, `{"id": "1", "displayName": "Jane", "avatar": null, "tags": [], "labels": {}}`, body)
+
+	// the body takes precedence over the query, and null leaves a field unset.
+	code, body = post(t, "/v1/profiles/1?displayName=Jane", `{"displayName": "John", "avatar": null, "address": null}`)
+	require.Equal(t, http.StatusOK, code)
+	require.JSONEq(t, `{"id": "1", "displayName": "John", "avatar": null, "tags": [], "labels": {}}`, body)
+
+	code, _ = post(t, "/v1/profiles/1?id=2", `{}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = post(t, "/v1/profiles/1?displayName=a&displayName=b", `{}`)
+	require.Equal(t, http.StatusBadRequest, code)
+}
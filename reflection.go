@@ -0,0 +1,187 @@
+package ghb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// RegisterReflectionProxy discovers the services of the grpc server behind cc
+// with its grpc.reflection.v1 service, and serves their http rules by
+// forwarding the calls to cc, see RegisterProxy. The services are discovered
+// again every refresh interval until ctx is done, methods added to the
+// backend are served from then on. Routes are never removed, the rules of
+// methods already served are kept until a restart, and methods with a rule
+// conflicting with a served route are logged and skipped. A zero refresh
+// disables it.
+func (s *Server) RegisterReflectionProxy(ctx context.Context, cc grpc.ClientConnInterface, refresh time.Duration) error {
+	if err := s.refreshProxy(ctx, cc); err != nil {
+		return err
+	}
+	if refresh <= 0 {
+		return nil
+	}
+	go func() {
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := s.refreshProxy(ctx, cc); err != nil && ctx.Err() == nil {
+				log.Printf("ghb: failed to refresh services: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// refreshProxy registers the services exposed by the backend which are not
+// served in process, and the routes of their new methods once serving.
+func (s *Server) refreshProxy(ctx context.Context, cc grpc.ClientConnInterface) error {
+	files, err := reflectFiles(ctx, cc)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var services []string
+	for name, sd := range files {
+		if info, ok := s.services[name]; ok && info.impl != nil {
+			continue
+		}
+		s.registerProxy(sd, cc)
+		services = append(services, name)
+	}
+	if !s.protosRegistered {
+		// doRegisterProtos registers the routes.
+		return nil
+	}
+	// a service failing to register, such as with a rule conflicting with a
+	// route, leaves the others and its methods registered already served.
+	var errs []error
+	for _, name := range services {
+		if err := s.registerService(s.services[name].desc); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reflectFiles returns the descriptors of the services listed by the
+// reflection service of the backend, by name.
+func reflectFiles(ctx context.Context, cc grpc.ClientConnInterface) (map[string]protoreflect.ServiceDescriptor, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := rpb.NewServerReflectionClient(cc).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := reflectionRequest(stream, &rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, service := range res.GetListServicesResponse().GetService() {
+		if !strings.HasPrefix(service.GetName(), "grpc.reflection.") {
+			names = append(names, service.GetName())
+		}
+	}
+
+	fdps := make(map[string]*descriptorpb.FileDescriptorProto)
+	var order []*descriptorpb.FileDescriptorProto
+	add := func(res *rpb.ServerReflectionResponse) error {
+		for _, b := range res.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fdp := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(b, fdp); err != nil {
+				return fmt.Errorf("invalid file descriptor: %w", err)
+			}
+			if _, ok := fdps[fdp.GetName()]; !ok {
+				fdps[fdp.GetName()] = fdp
+				order = append(order, fdp)
+			}
+		}
+		return nil
+	}
+	for _, name := range names {
+		res, err := reflectionRequest(stream, &rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: name},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := add(res); err != nil {
+			return nil, err
+		}
+	}
+	// the backend sends the dependencies of the files along with them, the
+	// ones it left out are fetched by name unless linked into the binary.
+	for i := 0; i < len(order); i++ {
+		for _, dep := range order[i].GetDependency() {
+			if _, ok := fdps[dep]; ok {
+				continue
+			}
+			if _, err := protoregistry.GlobalFiles.FindFileByPath(dep); err == nil {
+				continue
+			}
+			res, err := reflectionRequest(stream, &rpb.ServerReflectionRequest{
+				MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := add(res); err != nil {
+				return nil, err
+			}
+		}
+	}
+	stream.CloseSend()
+
+	files, err := newFiles(order)
+	if err != nil {
+		return nil, err
+	}
+	services := make(map[string]protoreflect.ServiceDescriptor, len(names))
+	for _, name := range names {
+		desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, err
+		}
+		sd, ok := desc.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a service", name)
+		}
+		services[name] = sd
+	}
+	return services, nil
+}
+
+func reflectionRequest(stream rpb.ServerReflection_ServerReflectionInfoClient, req *rpb.ServerReflectionRequest) (*rpb.ServerReflectionResponse, error) {
+	if err := stream.Send(req); err != nil {
+		return nil, err
+	}
+	res, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if e := res.GetErrorResponse(); e != nil {
+		return nil, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+	}
+	return res, nil
+}
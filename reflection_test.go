@@ -0,0 +1,119 @@
+package ghb
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/malayanand/ghb/api"
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+// testServiceInfo lists the services of the backend to the reflection
+// service, the service is listed once deployed.
+type testServiceInfo struct {
+	server   *grpc.Server
+	service  string
+	deployed *atomic.Bool
+}
+
+func (p testServiceInfo) GetServiceInfo() map[string]grpc.ServiceInfo {
+	services := p.server.GetServiceInfo()
+	if p.deployed.Load() {
+		services[p.service] = grpc.ServiceInfo{}
+	}
+	return services
+}
+
+func TestServer_reflectionProxy(t *testing.T) {
+	files, err := LoadFileDescriptorSet("testdata/greeter.binpb")
+	require.NoError(t, err)
+	deployed := new(atomic.Bool)
+	conn := newTestGreeter(t, files, func(backend *grpc.Server) {
+		test.RegisterTestServiceServer(backend, testService{})
+		rpb.RegisterServerReflectionServer(backend, reflection.NewServerV1(reflection.ServerOptions{
+			Services:           testServiceInfo{server: backend, service: "ghb.greeter.Greeter", deployed: deployed},
+			DescriptorResolver: filesResolver{files},
+		}))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewServer()
+	require.NoError(t, s.RegisterReflectionProxy(ctx, conn, 10*time.Millisecond))
+	addr := serveTest(t, s)
+
+	res, err := http.Get("http://" + addr + "/v1/users/123")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = http.Get("http://" + addr + "/v1/greet/jane")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	deployed.Store(true)
+	require.Eventually(t, func() bool {
+		res, err := http.Get("http://" + addr + "/v1/greet/jane")
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServer_reflectionProxyConflict(t *testing.T) {
+	files, err := LoadFileDescriptorSet("testdata/greeter.binpb")
+	require.NoError(t, err)
+	rules := ruleFiles(t, &api.HttpRule{Path: "/v1/users/{name}", Method: api.HttpRule_HttpMethod_GET})
+	deployed := new(atomic.Bool)
+	conn := newTestGreeter(t, files, func(backend *grpc.Server) {
+		test.RegisterTestServiceServer(backend, testService{})
+		rpb.RegisterServerReflectionServer(backend, reflection.NewServerV1(reflection.ServerOptions{
+			Services:           testServiceInfo{server: backend, service: "ghb.rules.Rules", deployed: deployed},
+			DescriptorResolver: filesResolver{rules},
+		}))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewServer()
+	require.NoError(t, s.RegisterReflectionProxy(ctx, conn, 10*time.Millisecond))
+	addr := serveTest(t, s)
+
+	// the rule of the new service conflicts with the route of GetUser, the
+	// method is skipped while the rest of the service is served.
+	deployed.Store(true)
+	require.Eventually(t, func() bool {
+		res, err := http.Post("http://"+addr+"/ghb.rules.Rules/Call", contentTypeJSON, strings.NewReader(`{}`))
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode != http.StatusNotFound
+	}, 5*time.Second, 10*time.Millisecond)
+
+	res, err := http.Get("http://" + addr + "/v1/users/123")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestServer_reflectionProxyUnavailable(t *testing.T) {
+	files, err := LoadFileDescriptorSet("testdata/greeter.binpb")
+	require.NoError(t, err)
+	// the backend has no reflection service.
+	conn := newTestGreeter(t, files, nil)
+
+	err = NewServer().RegisterReflectionProxy(context.Background(), conn, 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,18 @@
+package ghb
+
+import "google.golang.org/grpc"
+
+type multiRegistrar []grpc.ServiceRegistrar
+
+// MultiRegistrar returns a grpc.ServiceRegistrar registering the services
+// with every registrar, e.g. the same implementations with a grpc.Server and
+// a ghb Server.
+func MultiRegistrar(registrars ...grpc.ServiceRegistrar) grpc.ServiceRegistrar {
+	return multiRegistrar(registrars)
+}
+
+func (m multiRegistrar) RegisterService(serviceDesc *grpc.ServiceDesc, impl any) {
+	for _, r := range m {
+		r.RegisterService(serviceDesc, impl)
+	}
+}
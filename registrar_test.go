@@ -0,0 +1,92 @@
+package ghb
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestServer_RegisterServiceErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(s *Server)
+		wantErr  string
+	}{
+		{
+			name: "duplicate",
+			register: func(s *Server) {
+				test.RegisterTestServiceServer(s, testService{})
+				test.RegisterTestServiceServer(s, testService{})
+			},
+			wantErr: `ghb: found duplicate service registration for "ghb.test.TestService"`,
+		},
+		{
+			name: "invalid impl",
+			register: func(s *Server) {
+				s.RegisterService(&test.TestService_ServiceDesc, struct{}{})
+			},
+			wantErr: "ghb: the handler of type struct {} does not satisfy test.TestServiceServer",
+		},
+		{
+			name: "duplicate proxy",
+			register: func(s *Server) {
+				test.RegisterTestServiceServer(s, testService{})
+				s.RegisterProxy(&test.TestService_ServiceDesc, nil)
+			},
+			wantErr: `ghb: found duplicate service registration for "ghb.test.TestService"`,
+		},
+		{
+			name: "unknown proxy",
+			register: func(s *Server) {
+				test.RegisterTestServiceServer(s, testService{})
+				s.RegisterProxy(&grpc.ServiceDesc{ServiceName: "ghb.test.Unknown"}, nil)
+			},
+			wantErr: `ghb: service "ghb.test.Unknown" not found`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			tt.register(s)
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer lis.Close()
+			err = s.Serve(lis)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestServer_RegisterServiceAfterServe(t *testing.T) {
+	s := NewServer()
+	test.RegisterTestServiceServer(s, testService{})
+	addr := serveTest(t, s)
+	_, err := s.OpenAPI()
+	require.NoError(t, err)
+
+	// the registration is logged and ignored.
+	test.RegisterTestServiceServer(s, testService{})
+	res, err := http.Get("http://" + addr + "/v1/users/123")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestMultiRegistrar(t *testing.T) {
+	first, second := NewServer(), NewServer()
+	test.RegisterTestServiceServer(MultiRegistrar(first, second), testService{})
+
+	for _, s := range []*Server{first, second} {
+		res, err := http.Get("http://" + serveTest(t, s) + "/v1/users/123")
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id": "123", "name": "John Doe", "age": 30}`, string(body))
+	}
+}
@@ -0,0 +1,193 @@
+package ghb
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/malayanand/ghb/api"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const emptyName = "google.protobuf.Empty"
+
+// isEmpty reports whether md is google.protobuf.Empty, whose responses have
+// no body.
+func isEmpty(md protoreflect.MessageDescriptor) bool {
+	return md.FullName() == emptyName
+}
+
+// successCode returns the status code of successful responses of the rule.
+func successCode(httpRule *api.HttpRule) int {
+	if httpRule.GetSuccessCode() != 0 {
+		return int(httpRule.GetSuccessCode())
+	}
+	return http.StatusOK
+}
+
+// checkSuccessCode reports a success_code of the rule which is not a 2xx
+// status code, which could not be written as the status of a success.
+func checkSuccessCode(method protoreflect.MethodDescriptor, httpRule *api.HttpRule) error {
+	code := httpRule.GetSuccessCode()
+	if code != 0 && (code < http.StatusOK || code >= http.StatusMultipleChoices) {
+		return fmt.Errorf("ghb: success_code %d of method %s is not a 2xx status code", code, method.FullName())
+	}
+	return nil
+}
+
+// expandLocation fills the variables of a location template with the values
+// of the response fields they name, dotted paths of json names select the
+// fields of nested messages.
+func expandLocation(template string, msg protoreflect.Message) (string, error) {
+	var b strings.Builder
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			b.WriteString(rest)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable in location %q", template)
+		}
+		value, err := fieldString(msg, strings.Split(rest[start+1:start+end], "."))
+		if err != nil {
+			return "", fmt.Errorf("location %q: %v", template, err)
+		}
+		b.WriteString(rest[:start])
+		b.WriteString(url.PathEscape(value))
+		rest = rest[start+end+1:]
+	}
+}
+
+func fieldString(msg protoreflect.Message, path []string) (string, error) {
+	keys, err := descriptorKeys(msg.Descriptor())
+	if err != nil {
+		return "", err
+	}
+	protoKey, ok := keys[path[0]]
+	if !ok {
+		return "", fmt.Errorf("field %s not found", path[0])
+	}
+	fd := msg.Descriptor().Fields().ByName(protoreflect.Name(protoKey))
+	switch {
+	case fd.IsList() || fd.IsMap():
+		return "", fmt.Errorf("field %s is not a single value", path[0])
+	case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+		if len(path) == 1 {
+			return "", fmt.Errorf("field %s is a message", path[0])
+		}
+		return fieldString(msg.Get(fd).Message(), path[1:])
+	case len(path) > 1:
+		return "", fmt.Errorf("field %s is not a message", path[0])
+	case fd.Kind() == protoreflect.EnumKind:
+		value := fd.Enum().Values().ByNumber(msg.Get(fd).Enum())
+		if value == nil {
+			return fmt.Sprint(msg.Get(fd).Enum()), nil
+		}
+		return string(value.Name()), nil
+	case fd.Kind() == protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(msg.Get(fd).Bytes()), nil
+	}
+	return fmt.Sprint(msg.Get(fd).Interface()), nil
+}
+
+// omitFromBody reports whether the field is left out of response bodies.
+func omitFromBody(fd protoreflect.FieldDescriptor) bool {
+	return proto.GetExtension(fd.Options(), api.E_Field).(*api.FieldRule).GetOmitFromBody()
+}
+
+// responseHeader returns the name of the response header the field is sent
+// in, if any.
+func responseHeader(fd protoreflect.FieldDescriptor) string {
+	return proto.GetExtension(fd.Options(), api.E_Field).(*api.FieldRule).GetResponseHeader()
+}
+
+// checkResponseHeaders reports a response_header on a field of the output of
+// method which does not hold a single scalar value.
+func checkResponseHeaders(method protoreflect.MethodDescriptor) error {
+	fields := method.Output().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if responseHeader(fd) == "" {
+			continue
+		}
+		if fd.IsList() || fd.IsMap() || fd.Message() != nil {
+			return fmt.Errorf("ghb: response_header of field %s of method %s is not on a scalar field", fd.FullName(), method.FullName())
+		}
+	}
+	return nil
+}
+
+// setResponseHeaders sets the response headers bound to the fields of msg.
+// Fields with presence are left out when unset, the others are always sent.
+func setResponseHeaders(header http.Header, msg protoreflect.Message) error {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := responseHeader(fd)
+		if name == "" || fd.HasPresence() && !msg.Has(fd) {
+			continue
+		}
+		value, err := fieldString(msg, []string{jsonKey(fd)})
+		if err != nil {
+			return fmt.Errorf("response header %s: %v", name, err)
+		}
+		header.Set(name, value)
+	}
+	return nil
+}
+
+// omittingCodec leaves the fields marked omit_from_body out of the bodies of
+// http rule responses, the only responses whose headers carry them.
+type omittingCodec struct {
+	messageCodec
+}
+
+func (c omittingCodec) marshal(msg proto.Message) ([]byte, error) {
+	vc, ok := c.messageCodec.(valueCodec)
+	if !ok {
+		return c.messageCodec.marshal(withoutOmittedFields(msg))
+	}
+	value, err := marshalMessage(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response body: %v", err)
+	}
+	if fields, ok := value.(map[string]any); ok {
+		for _, fd := range omittedFields(msg.ProtoReflect().Descriptor()) {
+			delete(fields, jsonKey(fd))
+		}
+	}
+	return vc.Codec.Marshal(value)
+}
+
+// withoutOmittedFields returns a copy of msg without the fields marked
+// omit_from_body.
+func withoutOmittedFields(msg proto.Message) proto.Message {
+	omitted := omittedFields(msg.ProtoReflect().Descriptor())
+	if len(omitted) == 0 {
+		return msg
+	}
+	msg = proto.Clone(msg)
+	for _, fd := range omitted {
+		msg.ProtoReflect().Clear(fd)
+	}
+	return msg
+}
+
+// omittedFields returns the fields of md marked omit_from_body, only the
+// fields of the response message itself are sent as headers.
+func omittedFields(md protoreflect.MessageDescriptor) []protoreflect.FieldDescriptor {
+	var omitted []protoreflect.FieldDescriptor
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if omitFromBody(fields.Get(i)) {
+			omitted = append(omitted, fields.Get(i))
+		}
+	}
+	return omitted
+}
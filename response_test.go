@@ -0,0 +1,97 @@
+package ghb
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/malayanand/ghb/api"
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func Test_responseHeaders(t *testing.T) {
+	summary := &test.UploadSummary{Files: []string{"a.txt"}, Size: 5, Checksum: "3610a686"}
+
+	header := http.Header{}
+	require.NoError(t, setResponseHeaders(header, summary.ProtoReflect()))
+	require.Equal(t, "3610a686", header.Get("X-Checksum"))
+
+	header = http.Header{}
+	require.NoError(t, setResponseHeaders(header, (&test.UploadSummary{}).ProtoReflect()))
+	require.Equal(t, http.Header{"X-Checksum": {""}}, header)
+
+	body, err := omittingCodec{valueCodec{jsonCodec{}}}.marshal(summary)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"files": ["a.txt"], "size": 5}`, string(body))
+
+	body, err = omittingCodec{protoCodec{}}.marshal(summary)
+	require.NoError(t, err)
+	actual := &test.UploadSummary{}
+	require.NoError(t, proto.Unmarshal(body, actual))
+	require.Empty(t, actual.Checksum)
+	require.Equal(t, "3610a686", summary.Checksum)
+
+	body, err = marshalBytes(summary)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"files": ["a.txt"], "size": 5, "checksum": "3610a686"}`, string(body))
+}
+
+func TestServer_responseHeaders(t *testing.T) {
+	addr := newTestServer(t)
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("name", "a.txt")
+	fw, _ := mw.CreateFormFile("data", "a.txt")
+	fw.Write([]byte("hello"))
+	mw.Close()
+	res, err := http.Post("http://"+addr+"/v1/uploads/docs", mw.FormDataContentType(), &buf)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "3610a686", res.Header.Get("X-Checksum"))
+	require.JSONEq(t, `{"files": ["docs/a.txt"], "size": 5}`, string(body))
+}
+
+func TestServer_invalidResponseHeader(t *testing.T) {
+	header := func(label descriptorpb.FieldDescriptorProto_Label, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		options := &descriptorpb.FieldOptions{}
+		proto.SetExtension(options, api.E_Field, &api.FieldRule{ResponseHeader: "X-Value"})
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String("value"),
+			JsonName: proto.String("value"),
+			Number:   proto.Int32(1),
+			Label:    label.Enum(),
+			Type:     typ.Enum(),
+			Options:  options,
+		}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		return fd
+	}
+	rule := &api.HttpRule{Path: "/v1/call", Method: api.HttpRule_HttpMethod_POST}
+
+	for _, fd := range []*descriptorpb.FieldDescriptorProto{
+		header(descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".ghb.rules.Reply"),
+		header(descriptorpb.FieldDescriptorProto_LABEL_REPEATED, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+	} {
+		s := NewServer()
+		s.RegisterService(&test.TestService_ServiceDesc, testService{})
+		s.RegisterProxyFiles(ruleFiles(t, rule, fd), nil)
+		require.ErrorContains(t, serveErr(t, s), "response_header of field ghb.rules.Reply.value of method ghb.rules.Rules.Call is not on a scalar field")
+	}
+
+	s := NewServer()
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	s.RegisterProxyFiles(ruleFiles(t, rule, header(descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_INT32, "")), nil)
+	serveTest(t, s)
+	require.NoError(t, s.registerProtosOnce())
+}
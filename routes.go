@@ -0,0 +1,130 @@
+package ghb
+
+import (
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// handle registers the handler for the method and the path of a rule, and
+// records the route so requests with any other method for the path are
+// answered with the methods allowed for it by serveFallback.
+func (s *Server) handle(method, rulePath string, handler http.HandlerFunc) {
+	pattern := path.Join("/", rulePath)
+	if pattern == "/" {
+		// a bare "/" would match every path.
+		pattern = "/{$}"
+	}
+	// the mux panics on a conflicting pattern, the route is only recorded
+	// once it is registered.
+	s.mux.HandleFunc(method+" "+pattern, handler)
+	key := routeKey(pattern)
+	s.routes[key] = append(s.routes[key], method)
+}
+
+// serveFallback serves the requests no handler is registered for, with 405
+// Method Not Allowed if a route matches the path for other methods and with
+// 404 Not Found otherwise. The mux already serves HEAD requests with the
+// handler for GET.
+func (s *Server) serveFallback(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	methods := s.routeMethods(r.URL.EscapedPath())
+	s.mu.RUnlock()
+	if len(methods) == 0 {
+		notFound(w, r)
+		return
+	}
+	if r.Method != http.MethodOptions {
+		methodNotAllowed(w, r.Method, allowedMethods(methods))
+		return
+	}
+	if s.cors != nil && r.Header.Get("Access-Control-Request-Method") != "" && w.Header().Get("Access-Control-Allow-Origin") != "" {
+		s.preflight(w, r, methods)
+		return
+	}
+	w.Header().Set("Allow", allowedMethods(methods))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// routeMethods returns the methods of the routes matching the escaped path,
+// in the order they were registered in for each route.
+func (s *Server) routeMethods(escapedPath string) []string {
+	segments := strings.Split(escapedPath, "/")
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segments[i] = unescaped
+		}
+	}
+	var methods []string
+	for _, key := range slices.Sorted(maps.Keys(s.routes)) {
+		if !routeMatches(key, segments) {
+			continue
+		}
+		for _, method := range s.routes[key] {
+			if !slices.Contains(methods, method) {
+				methods = append(methods, method)
+			}
+		}
+	}
+	return methods
+}
+
+// routeMatches reports whether the route key matches the segments of a path
+// the way the pattern of the key does in the mux.
+func routeMatches(key string, segments []string) bool {
+	keySegments := strings.Split(key, "/")
+	for i, keySegment := range keySegments {
+		if i >= len(segments) {
+			return false
+		}
+		switch keySegment {
+		case "{...}":
+			return true
+		case "{}":
+			if segments[i] == "" {
+				return false
+			}
+		default:
+			if keySegment != segments[i] {
+				return false
+			}
+		}
+	}
+	return len(keySegments) == len(segments)
+}
+
+// allowedMethods returns the value of the Allow header of a route registered
+// for the given methods.
+func allowedMethods(methods []string) string {
+	allow := slices.Clone(methods)
+	if slices.Contains(allow, http.MethodGet) {
+		allow = append(allow, http.MethodHead)
+	}
+	allow = append(allow, http.MethodOptions)
+	slices.Sort(allow)
+	return strings.Join(slices.Compact(allow), ", ")
+}
+
+// routeKey drops the names of the wildcards of a pattern, patterns differing
+// only in them match the same requests. The {$} anchoring a trailing slash
+// is dropped as well.
+func routeKey(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") {
+			continue
+		}
+		switch {
+		case segment == "{$}":
+			segments[i] = ""
+		case strings.HasSuffix(segment, "...}"):
+			segments[i] = "{...}"
+		default:
+			segments[i] = "{}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,94 @@
+package ghb
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_routeKey(t *testing.T) {
+	require.Equal(t, "/v1/users/{}", routeKey("/v1/users/{id}"))
+	require.Equal(t, "/v1/{}/files/{...}", routeKey("/v1/{folder}/files/{path...}"))
+	require.Equal(t, "/v1/users", routeKey("/v1/users"))
+	require.Equal(t, "/", routeKey("/{$}"))
+}
+
+func TestServer_overlappingRoutes(t *testing.T) {
+	s := NewServer()
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Pattern))
+	}
+	// neither pattern is more specific than the other.
+	s.handle(http.MethodGet, "/v1/{a}/x", ok)
+	s.handle(http.MethodPost, "/v1/x/{b}", ok)
+	s.handle(http.MethodGet, "/", ok)
+	s.handle(http.MethodPut, "/v1/files/{path...}", ok)
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+		wantAllow  string
+	}{
+		{method: http.MethodGet, path: "/v1/y/x", wantStatus: http.StatusOK},
+		{method: http.MethodPost, path: "/v1/x/y", wantStatus: http.StatusOK},
+		{method: http.MethodDelete, path: "/v1/x/x", wantStatus: http.StatusMethodNotAllowed, wantAllow: "GET, HEAD, OPTIONS, POST"},
+		{method: http.MethodPost, path: "/v1/y/x", wantStatus: http.StatusMethodNotAllowed, wantAllow: "GET, HEAD, OPTIONS"},
+		{method: http.MethodGet, path: "/", wantStatus: http.StatusOK},
+		{method: http.MethodPost, path: "/", wantStatus: http.StatusMethodNotAllowed, wantAllow: "GET, HEAD, OPTIONS"},
+		{method: http.MethodGet, path: "/v1/y", wantStatus: http.StatusNotFound},
+		{method: http.MethodGet, path: "/v1/files/a/b", wantStatus: http.StatusMethodNotAllowed, wantAllow: "OPTIONS, PUT"},
+		{method: http.MethodGet, path: "/v1/files", wantStatus: http.StatusNotFound},
+		{method: http.MethodOptions, path: "/v1/%79/x", wantStatus: http.StatusNoContent, wantAllow: "GET, HEAD, OPTIONS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			require.Equal(t, tt.wantStatus, w.Code)
+			require.Equal(t, tt.wantAllow, w.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestServer_routeFallbacks(t *testing.T) {
+	addr := newTestServer(t)
+
+	do := func(t *testing.T, method, path string) (*http.Response, string) {
+		req, err := http.NewRequest(method, "http://"+addr+path, nil)
+		require.NoError(t, err)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(body)
+	}
+
+	t.Run("method not allowed", func(t *testing.T) {
+		res, body := do(t, http.MethodPut, "/v1/users/123")
+		require.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+		require.Equal(t, "DELETE, GET, HEAD, OPTIONS", res.Header.Get("Allow"))
+		require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		require.JSONEq(t, `{"code": 12, "message": "method PUT not allowed, allowed are DELETE, GET, HEAD, OPTIONS", "details": []}`, body)
+	})
+	t.Run("not found", func(t *testing.T) {
+		res, body := do(t, http.MethodGet, "/v1/unknown")
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.JSONEq(t, `{"code": 5, "message": "no route for GET /v1/unknown", "details": []}`, body)
+	})
+	t.Run("head", func(t *testing.T) {
+		res, body := do(t, http.MethodHead, "/v1/users/123")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		require.Empty(t, body)
+	})
+	t.Run("options", func(t *testing.T) {
+		res, _ := do(t, http.MethodOptions, "/v1/uploads/docs")
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		require.Equal(t, "GET, HEAD, OPTIONS, POST", res.Header.Get("Allow"))
+	})
+}
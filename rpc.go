@@ -0,0 +1,58 @@
+package ghb
+
+import (
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// rpcMethod is a method of a registered service, called by its full name
+// rather than through an http rule.
+type rpcMethod struct {
+	name   string
+	impl   any
+	desc   protoreflect.MethodDescriptor
+	unary  *grpc.MethodDesc
+	stream *grpc.StreamDesc
+}
+
+// lookupMethod returns the method with the full name "/package.Service/Method"
+// of a registered service, unknown methods are Unimplemented like in grpc.
+func (s *Server) lookupMethod(fullMethod string) (*rpcMethod, error) {
+	serviceName, methodName, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok || serviceName == "" || methodName == "" || strings.Contains(methodName, "/") {
+		return nil, status.Errorf(codes.Unimplemented, "malformed method name %q", fullMethod)
+	}
+	s.mu.RLock()
+	info, ok := s.services[serviceName]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "unknown service %s", serviceName)
+	}
+	sd := info.desc
+	if sd == nil {
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(serviceName))
+		if err != nil {
+			return nil, status.Errorf(codes.Unimplemented, "unknown service %s", serviceName)
+		}
+		if sd, ok = desc.(protoreflect.ServiceDescriptor); !ok {
+			return nil, status.Errorf(codes.Unimplemented, "unknown service %s", serviceName)
+		}
+	}
+	md := sd.Methods().ByName(protoreflect.Name(methodName))
+	method := &rpcMethod{
+		name:   "/" + serviceName + "/" + methodName,
+		impl:   info.impl,
+		desc:   md,
+		unary:  info.methods[methodName],
+		stream: info.streams[methodName],
+	}
+	if md == nil || (method.unary == nil && method.stream == nil) {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s for service %s", methodName, serviceName)
+	}
+	return method, nil
+}
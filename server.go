@@ -1,20 +1,26 @@
 package ghb
 
 import (
-	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
+	"mime"
 	"net"
 	"net/http"
-	"path"
 	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 
 	"github.com/malayanand/ghb/api"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 )
@@ -22,13 +28,49 @@ import (
 type Server struct {
 	registerProtoOnce sync.Once
 	registerProtoErr  error
-	services          map[string]*serviceInfo
-	mux               *http.ServeMux
+	// mu guards the services and routes registered while serving, by the
+	// refresh of services discovered with reflection.
+	mu               sync.RWMutex
+	protosRegistered bool
+	registerErr      error
+	services         map[string]*serviceInfo
+	codecs           map[string]messageCodec
+	mux              *http.ServeMux
+	routes           map[string][]string
+	rules            []registeredRule
+	cors             *CORSPolicy
+	openAPIPath      string
+	openAPIInfo      OpenAPIInfo
+	jsonRPCPath      string
+	batchPath        string
+	batchConcurrency int
+	maxBatchRequests int
+	maxBatchResponse int64
+	maxBatchTotal    int64
+	health           grpc_health_v1.HealthServer
+	shutdownDelay    time.Duration
+	httpServers      []*http.Server
+	shuttingDown     atomic.Bool
+
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+
+	compressors          map[string]Compressor
+	compressorNames      []string
+	compressionThreshold int
+	maxDecompressedSize  int64
+	maxFormPartSize      int64
+	maxBodySize          int64
+	limits               decodeLimits
 }
 
 type serviceInfo struct {
 	impl    any
 	methods map[string]*grpc.MethodDesc
+	streams map[string]*grpc.StreamDesc
+	// desc is the descriptor of services loaded at runtime, which are not
+	// in protoregistry.GlobalFiles.
+	desc protoreflect.ServiceDescriptor
 }
 
 var (
@@ -37,30 +79,92 @@ var (
 	httpRuleName        = "ghb.api.http"
 )
 
-func NewServer() *Server {
-	return &Server{
-		services: make(map[string]*serviceInfo),
-		mux:      http.NewServeMux(),
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{
+		services:             make(map[string]*serviceInfo),
+		codecs:               defaultCodecs(),
+		mux:                  http.NewServeMux(),
+		routes:               make(map[string][]string),
+		compressors:          defaultCompressors(),
+		compressorNames:      []string{"gzip", "deflate"},
+		compressionThreshold: defaultCompressionThreshold,
+		maxDecompressedSize:  defaultMaxDecompressedSize,
+		maxFormPartSize:      defaultMaxFormPartSize,
+		maxBodySize:          defaultMaxBodySize,
+		maxBatchRequests:     defaultMaxBatchRequests,
+		maxBatchResponse:     defaultMaxBatchResponse,
+		maxBatchTotal:        defaultMaxBatchTotal,
+		limits: decodeLimits{
+			maxDepth: defaultMaxDecodeDepth,
+		},
 	}
+	s.mux.HandleFunc("/", s.serveFallback)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
+var _ grpc.ServiceRegistrar = (*Server)(nil)
+
+// RegisterService registers a service and its implementation, which makes
+// Server a grpc.ServiceRegistrar accepted by the generated RegisterXServer
+// functions. Like grpc.Server, registering a service twice, an impl not
+// implementing the service, or registering after Serve are errors. They are
+// returned by Serve, errors after Serve are logged.
 func (s *Server) RegisterService(serviceDesc *grpc.ServiceDesc, impl any) {
-	expected := reflect.TypeOf(serviceDesc.HandlerType).Elem()
-	actual := reflect.TypeOf(impl)
-	if !actual.Implements(expected) {
-		log.Panicf("impl does not implement serviceDesc.HandlerType")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkRegistration(serviceDesc.ServiceName, serviceDesc, impl); err != nil {
+		s.registrationFailed(err)
+		return
 	}
 	s.register(serviceDesc, impl)
 }
 
+// checkRegistration validates the registration of a service, impl is only
+// checked against the handler type of serviceDesc if both are set.
+func (s *Server) checkRegistration(name string, serviceDesc *grpc.ServiceDesc, impl any) error {
+	if s.protosRegistered {
+		return fmt.Errorf("ghb: registration of service %q after Serve", name)
+	}
+	if serviceDesc != nil && impl != nil {
+		expected := reflect.TypeOf(serviceDesc.HandlerType).Elem()
+		actual := reflect.TypeOf(impl)
+		if !actual.Implements(expected) {
+			return fmt.Errorf("ghb: the handler of type %v does not satisfy %v", actual, expected)
+		}
+	}
+	if _, ok := s.services[name]; ok {
+		return fmt.Errorf("ghb: found duplicate service registration for %q", name)
+	}
+	return nil
+}
+
+// registrationFailed keeps the first registration error for Serve, as the
+// registration functions do not return errors.
+func (s *Server) registrationFailed(err error) {
+	if s.protosRegistered {
+		log.Print(err)
+		return
+	}
+	if s.registerErr == nil {
+		s.registerErr = err
+	}
+}
+
 func (s *Server) register(serviceDesc *grpc.ServiceDesc, impl any) {
 	info := &serviceInfo{
 		impl:    impl,
 		methods: make(map[string]*grpc.MethodDesc),
+		streams: make(map[string]*grpc.StreamDesc),
 	}
 	for _, method := range serviceDesc.Methods {
 		info.methods[method.MethodName] = &method
 	}
+	for _, stream := range serviceDesc.Streams {
+		info.streams[stream.StreamName] = &stream
+	}
 	s.services[serviceDesc.ServiceName] = info
 }
 
@@ -68,7 +172,49 @@ func (s *Server) Serve(lis net.Listener) error {
 	if err := s.registerProtosOnce(); err != nil {
 		return err
 	}
-	return http.Serve(lis, s.mux)
+	return s.serve(lis, s)
+}
+
+// ServeHTTP decompresses the request body and compresses the response around
+// the routes registered for the http rules and the Connect protocol. gRPC-Web
+// requests are served for the methods of the registered services.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if mediaType, ok := grpcWebContentType(r); ok {
+		if s.cors != nil {
+			s.cors.setHeaders(w.Header(), r)
+		}
+		s.serveGRPCWeb(w, r, mediaType)
+		return
+	}
+	body, err := s.decompressRequest(r)
+	if errors.Is(err, errUnsupportedEncoding) {
+		writeStatus(w, http.StatusUnsupportedMediaType, status.New(codes.InvalidArgument, err.Error()))
+		return
+	}
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	if body != nil {
+		defer body.Close()
+	}
+	if s.cors != nil {
+		s.cors.setHeaders(w.Header(), r)
+	}
+	if isWebSocketUpgrade(r) || isConnectStream(r) {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+	name, c := s.responseCompressor(r)
+	if c == nil {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+	cw := newCompressResponseWriter(w, name, c, s.compressionThreshold)
+	s.mux.ServeHTTP(cw, r)
+	if err := cw.close(); err != nil {
+		log.Printf("ghb: failed to finish the compressed response of %s %s: %v", r.Method, r.URL.Path, err)
+	}
 }
 
 func (s *Server) registerProtosOnce() error {
@@ -79,6 +225,12 @@ func (s *Server) registerProtosOnce() error {
 }
 
 func (s *Server) doRegisterProtos() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protosRegistered = true
+	if s.registerErr != nil {
+		return s.registerErr
+	}
 	var err error
 	protoregistry.GlobalFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
 		for i := 0; i < fd.Services().Len(); i++ {
@@ -89,80 +241,330 @@ func (s *Server) doRegisterProtos() error {
 		}
 		return true
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	for _, name := range s.loadedServices() {
+		if err := s.registerService(s.services[name].desc); err != nil {
+			return err
+		}
+	}
+	if s.openAPIPath != "" {
+		s.handle(http.MethodGet, s.openAPIPath, s.serveOpenAPI)
+	}
+	if s.jsonRPCPath != "" {
+		s.handle(http.MethodPost, s.jsonRPCPath, s.serveJSONRPC)
+	}
+	if s.batchPath != "" {
+		s.handle(http.MethodPost, s.batchPath, s.serveBatch)
+	}
+	if s.health != nil {
+		s.handle(http.MethodGet, healthzPath, s.serveHealthz)
+		s.handle(http.MethodGet, readyzPath, s.serveReadyz)
+	}
+	return nil
+}
+
+// hasRule reports whether the http rule of the method is registered already.
+func (s *Server) hasRule(method protoreflect.FullName) bool {
+	for _, r := range s.rules {
+		if r.method.FullName() == method {
+			return true
+		}
+	}
+	return false
+}
+
+// loadedServices returns the sorted names of the services registered with a
+// descriptor which is not in protoregistry.GlobalFiles.
+func (s *Server) loadedServices() []string {
+	var names []string
+	for name, info := range s.services {
+		if info.desc == nil {
+			continue
+		}
+		if _, err := protoregistry.GlobalFiles.FindDescriptorByName(info.desc.FullName()); err == nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 func (s *Server) registerService(service protoreflect.ServiceDescriptor) error {
+	var conflicts []error
 	methods := service.Methods()
 	for i := 0; i < methods.Len(); i++ {
 		method := methods.Get(i)
 		rule := proto.GetExtension(method.Options(), api.E_Http)
 		if rule == nil {
-			return nil
+			continue
 		}
 		httpRule, ok := rule.(*api.HttpRule)
 		if !ok || httpRule == nil {
-			return nil
+			continue
+		}
+		if s.hasRule(method.FullName()) {
+			continue
+		}
+		if err := checkSuccessCode(method, httpRule); err != nil {
+			return err
+		}
+		if err := checkResponseHeaders(method); err != nil {
+			return err
 		}
 		serviceInfo, ok := s.services[string(service.FullName())]
 		if !ok || serviceInfo == nil {
 			return fmt.Errorf("service %s not found", service.FullName())
 		}
+		if method.IsStreamingClient() || method.IsStreamingServer() {
+			streamDesc, ok := serviceInfo.streams[string(method.Name())]
+			if !ok || streamDesc == nil {
+				return fmt.Errorf("stream %s not found", method.Name())
+			}
+			if err := handleRoutes(method, func() { s.handleStreamRule(serviceInfo.impl, method, httpRule, streamDesc) }); err != nil {
+				conflicts = append(conflicts, err)
+				continue
+			}
+			s.rules = append(s.rules, registeredRule{method: method, rule: httpRule})
+			continue
+		}
 		methodDesc, ok := serviceInfo.methods[string(method.Name())]
 		if !ok || methodDesc == nil {
 			return fmt.Errorf("method %s not found", method.Name())
 		}
-		s.handleHttpRule(serviceInfo.impl, httpRule, methodDesc.Handler)
+		if err := handleRoutes(method, func() { s.handleHttpRule(serviceInfo.impl, method, httpRule, methodDesc.Handler) }); err != nil {
+			conflicts = append(conflicts, err)
+			continue
+		}
+		s.rules = append(s.rules, registeredRule{method: method, rule: httpRule})
 	}
+	if info, ok := s.services[string(service.FullName())]; ok {
+		s.handleConnectService(info, service)
+	}
+	return errors.Join(conflicts...)
+}
+
+// handleRoutes registers the routes of a method with register, and returns
+// the panic of the mux on a pattern conflicting with a registered route as
+// an error, services discovered by RegisterReflectionProxy are registered
+// while serving.
+func handleRoutes(method protoreflect.MethodDescriptor, register func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("rule of method %s conflicts with a registered route: %v", method.FullName(), r)
+		}
+	}()
+	register()
 	return nil
 }
 
-func (s *Server) handleHttpRule(impl any, httpRule *api.HttpRule, methodHandler grpc.MethodHandler) {
+// handleHttpRule serves a unary method, requests and responses of type
+// google.api.HttpBody, or with a field of it, skip the content negotiation
+// and carry the raw body.
+// Successful responses get the success_code and location of the rule, the
+// headers bound to response fields, and no body if the method returns
+// google.protobuf.Empty. The metadata set by the handler with grpc.SetHeader
+// and grpc.SetTrailer is sent as headers of any response.
+func (s *Server) handleHttpRule(impl any, method protoreflect.MethodDescriptor, httpRule *api.HttpRule, methodHandler grpc.MethodHandler) {
+	rawRequest := hasHttpBody(method.Input())
+	rawResponse := hasHttpBody(method.Output())
+	code := successCode(httpRule)
+	noBody := isEmpty(method.Output()) || !bodyAllowed(code)
+	bindings := fieldBindings(method.Input())
+	query := queryKeys(method.Input())
+	interceptor := s.unaryInterceptor()
+	fullMethod := fullMethodName(method)
 	handler := func(w http.ResponseWriter, r *http.Request) {
-		params, err := extractURLParams(httpRule.Path, r.URL.RawPath)
+		params, err := extractURLParams(httpRule.Path, r.URL.EscapedPath())
 		if err != nil {
 			badRequest(w, err)
 			return
 		}
+		if err := bindQuery(r, query, params); err != nil {
+			badRequest(w, err)
+			return
+		}
+		bound, err := bindRequest(r, bindings, params)
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+		if !s.limitBody(w, r, httpRule) {
+			return
+		}
+
+		var reqDecoder requestDecoder = httpBodyDecoder{}
+		if !rawRequest {
+			var ok bool
+			if reqDecoder, ok = s.requestDecoder(r); !ok {
+				unsupportedMediaType(w, r.Header.Get("Content-Type"))
+				return
+			}
+		}
+		var contentType string
+		var resCodec messageCodec
+		if !rawResponse && !noBody {
+			var ok bool
+			if contentType, resCodec, ok = s.responseCodec(r); !ok {
+				notAcceptable(w, r.Header.Get("Accept"))
+				return
+			}
+		}
 
-		// TODO: build on what to pass in context.
-		ctx := context.Background()
+		sts := &unaryTransportStream{method: fullMethod}
+		ctx := metadata.NewIncomingContext(r.Context(), incomingMetadata(r))
+		ctx = grpc.NewContextWithServerTransportStream(ctx, sts)
 		dec := func(in any) error {
 			msg, ok := in.(proto.Message)
 			if !ok {
 				return fmt.Errorf("unsported type: %T", in)
 			}
-			var body []byte
-			var err error
-			if r.Body != nil && r.ContentLength != 0 {
-				body, err = io.ReadAll(r.Body)
-				if err != nil {
+			if err := reqDecoder.decode(r, msg, params); err != nil {
+				if errors.Is(err, errBodyTooLarge) {
 					return err
 				}
+				return status.Errorf(codes.InvalidArgument, "failed to unmarshal request body: %v", err)
 			}
-			err = unmarshalBytes(body, msg, params)
-			if err != nil {
-				return fmt.Errorf("failed to unmarshal request body: %v", err)
+			if err := checkBindings(msg, bound); err != nil {
+				return status.Error(codes.InvalidArgument, err.Error())
 			}
 			return nil
 		}
 
-		res, err := methodHandler(impl, ctx, dec, nil)
+		res, err := methodHandler(impl, ctx, dec, interceptor)
+		sts.writeMetadata(w.Header())
 		if err != nil {
+			writeError(w, err)
+			return
+		}
+		msg, ok := res.(proto.Message)
+		if !ok {
+			internalServerErrorf(w, "wrong type %T, expected proto message", res)
+			return
+		}
+		if httpRule.GetLocation() != "" {
+			location, err := expandLocation(httpRule.GetLocation(), msg.ProtoReflect())
+			if err != nil {
+				internalServerError(w, err)
+				return
+			}
+			w.Header().Set("Location", location)
+		}
+		if err := setResponseHeaders(w.Header(), msg.ProtoReflect()); err != nil {
 			internalServerError(w, err)
 			return
 		}
-		body, err := marshalBytes(res)
+		if noBody {
+			w.WriteHeader(code)
+			return
+		}
+		if rawResponse {
+			writeHttpBody(w, msg, code)
+			return
+		}
+		body, err := resCodec.marshal(msg)
 		if err != nil {
 			internalServerError(w, err)
 			return
 		}
-		_, err = w.Write(body)
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(code)
+		w.Write(body)
+	}
+	s.handle(httpRule.Method.String(), httpRule.Path, handler)
+}
+
+// handleStreamRule serves a streaming method over a websocket, regardless of
+// the method in the rule the route is registered for GET as required by the
+// websocket handshake, from the same origin or one allowed by the CORS
+// policy. Client streaming methods with a rule for any other
+// method additionally accept multipart/form-data uploads on it, and server
+// streaming methods returning google.api.HttpBody are served as a plain
+// download to GET requests without the upgrade.
+func (s *Server) handleStreamRule(impl any, method protoreflect.MethodDescriptor, httpRule *api.HttpRule, streamDesc *grpc.StreamDesc) {
+	download := streamDesc.ServerStreams && !streamDesc.ClientStreams && isHttpBody(method.Output())
+	fullMethod := fullMethodName(method)
+	bindings := fieldBindings(method.Input())
+	query := queryKeys(method.Input())
+	requestParams := func(r *http.Request) (map[string]string, error) {
+		params, err := extractURLParams(httpRule.Path, r.URL.EscapedPath())
 		if err != nil {
-			internalServerError(w, err)
+			return nil, err
+		}
+		if err := bindQuery(r, query, params); err != nil {
+			return nil, err
+		}
+		if _, err := bindRequest(r, bindings, params); err != nil {
+			return nil, err
+		}
+		return params, nil
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if download && !isWebSocketUpgrade(r) {
+			params, err := requestParams(r)
+			if err != nil {
+				badRequest(w, err)
+				return
+			}
+			ctx := metadata.NewIncomingContext(r.Context(), incomingMetadata(r))
+			stream := newHttpBodyServerStream(ctx, w, params)
+			stream.finish(s.callStream(impl, fullMethod, streamDesc, stream))
+			return
+		}
+		if err := checkWebSocketHandshake(r); err != nil {
+			w.Header().Set("Sec-WebSocket-Version", "13")
+			writeStatus(w, http.StatusUpgradeRequired, status.New(codes.FailedPrecondition, err.Error()))
+			return
+		}
+		if err := s.checkWebSocketOrigin(r); err != nil {
+			writeStatus(w, http.StatusForbidden, status.New(codes.PermissionDenied, err.Error()))
+			return
+		}
+		params, err := requestParams(r)
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+
+		ctx := metadata.NewIncomingContext(r.Context(), incomingMetadata(r))
+		stream := newWSServerStream(ctx, w, r, params, s.limits)
+		stream.finish(s.callStream(impl, fullMethod, streamDesc, stream))
+	}
+	s.handle(http.MethodGet, httpRule.Path, handler)
+
+	if streamDesc.ServerStreams || httpRule.Method == api.HttpRule_HttpMethod_GET {
+		return
+	}
+	uploadHandler := func(w http.ResponseWriter, r *http.Request) {
+		params, err := requestParams(r)
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+		if !s.limitBody(w, r, httpRule) {
+			return
+		}
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != contentTypeMultipart {
+			unsupportedMediaType(w, r.Header.Get("Content-Type"))
 			return
 		}
+		mr, err := r.MultipartReader()
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+		contentType, resCodec, ok := s.responseCodec(r)
+		if !ok {
+			notAcceptable(w, r.Header.Get("Accept"))
+			return
+		}
+
+		ctx := metadata.NewIncomingContext(r.Context(), incomingMetadata(r))
+		stream := newUploadServerStream(ctx, w, mr, s.multipartDecoder(), params, contentType, resCodec)
+		stream.finish(s.callStream(impl, fullMethod, streamDesc, stream))
 	}
-	pattern := fmt.Sprintf("%s %s", httpRule.Method.String(), path.Join("/", httpRule.Path))
-	s.mux.HandleFunc(pattern, handler)
+	s.handle(httpRule.Method.String(), httpRule.Path, uploadHandler)
 }
@@ -0,0 +1,368 @@
+package ghb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/malayanand/ghb/api"
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type testService struct {
+	test.UnimplementedTestServiceServer
+}
+
+func (testService) GetUser(ctx context.Context, req *test.GetUserRequest) (*test.TestUser, error) {
+	if req.Id == "missing" {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if req.Tenant == "blocked" {
+		return nil, status.Error(codes.PermissionDenied, "tenant blocked")
+	}
+	if req.Session == "expired" {
+		return nil, status.Error(codes.Unauthenticated, "session expired")
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs("x-user-source", "directory")); err != nil {
+		return nil, err
+	}
+	return &test.TestUser{Id: req.Id, Name: "John Doe", Age: 30}, nil
+}
+
+func (testService) CreateUser(ctx context.Context, req *test.TestUser) (*test.TestUser, error) {
+	return req, nil
+}
+
+func (testService) InviteUser(ctx context.Context, req *test.TestUser) (*test.TestUser, error) {
+	return req, nil
+}
+
+func (testService) DeleteUser(ctx context.Context, req *test.GetUserRequest) (*emptypb.Empty, error) {
+	if req.Id == "missing" {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (testService) UpdateProfile(ctx context.Context, req *test.Profile) (*test.Profile, error) {
+	return req, nil
+}
+
+func (testService) Upload(stream grpc.ClientStreamingServer[test.UploadChunk, test.UploadSummary]) error {
+	summary := &test.UploadSummary{}
+	checksum := crc32.NewIEEE()
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			summary.Checksum = fmt.Sprintf("%08x", checksum.Sum32())
+			return stream.SendAndClose(summary)
+		}
+		if err != nil {
+			return err
+		}
+		summary.Files = append(summary.Files, chunk.Folder+"/"+chunk.Name)
+		summary.Size += int64(len(chunk.Data))
+		checksum.Write(chunk.Data)
+	}
+}
+
+func (testService) Export(ctx context.Context, req *test.ExportRequest) (*httpbody.HttpBody, error) {
+	return &httpbody.HttpBody{ContentType: "text/" + req.Format, Data: []byte("id,name\n1,Jane\n")}, nil
+}
+
+func (testService) UploadAvatar(ctx context.Context, req *test.UploadAvatarRequest) (*httpbody.HttpBody, error) {
+	return &httpbody.HttpBody{
+		ContentType: req.Avatar.ContentType,
+		Data:        append([]byte(req.Id+":"), req.Avatar.Data...),
+	}, nil
+}
+
+func (testService) Render(ctx context.Context, req *test.ExportRequest) (*test.Rendering, error) {
+	return &test.Rendering{
+		Format: req.Format,
+		Body:   &httpbody.HttpBody{ContentType: "text/" + req.Format, Data: []byte("<p>Jane</p>")},
+	}, nil
+}
+
+func (testService) Download(req *test.ExportRequest, stream grpc.ServerStreamingServer[httpbody.HttpBody]) error {
+	for _, chunk := range []string{"id,name\n", "1,Jane\n", "2,John\n"} {
+		if err := stream.Send(&httpbody.HttpBody{ContentType: "text/" + req.Format, Data: []byte(chunk)}); err != nil {
+			return err
+		}
+	}
+	if req.Format == "broken" {
+		return status.Error(codes.DataLoss, "export interrupted")
+	}
+	return nil
+}
+
+func (testService) Chat(stream grpc.BidiStreamingServer[test.ChatMessage, test.ChatMessage]) error {
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok && len(md.Get("x-user")) > 0 {
+		if err := stream.SetHeader(metadata.Pairs("x-greeting", "hello "+md.Get("x-user")[0])); err != nil {
+			return err
+		}
+	}
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Text == "bye" {
+			return status.Error(codes.Aborted, "conversation ended")
+		}
+		if err := stream.Send(&test.ChatMessage{Room: msg.Room, Text: "echo: " + msg.Text}); err != nil {
+			return err
+		}
+	}
+}
+
+func (testService) Ping(ctx context.Context, req *emptypb.Empty) (*emptypb.Empty, error) {
+	if err := grpc.SetHeader(ctx, metadata.Pairs("x-pong", "1")); err != nil {
+		return nil, err
+	}
+	if err := grpc.SetTrailer(ctx, metadata.Pairs("x-served-by", "ghb")); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func newTestServer(t *testing.T) string {
+	t.Helper()
+	s := NewServer()
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	return serveTest(t, s)
+}
+
+func serveTest(t *testing.T, s *Server) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	t.Cleanup(func() { lis.Close() })
+	return lis.Addr().String()
+}
+
+func TestServer_unary(t *testing.T) {
+	addr := newTestServer(t)
+
+	res, err := http.Get("http://" + addr + "/v1/users/123")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.JSONEq(t, `{"id": "123", "name": "John Doe", "age": 30}`, string(body))
+}
+
+func TestServer_contentNegotiation(t *testing.T) {
+	addr := newTestServer(t)
+	user := &test.TestUser{Id: "1", Name: "Jane", Age: 28}
+	protoBody, err := proto.Marshal(user)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		contentType     string
+		accept          string
+		body            []byte
+		wantStatus      int
+		wantContentType string
+	}{
+		{
+			name:            "json by default",
+			body:            []byte(`{"id": "1", "name": "Jane", "age": 28}`),
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "protobuf request and response",
+			contentType:     "application/x-protobuf",
+			accept:          "application/x-protobuf",
+			body:            protoBody,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-protobuf",
+		},
+		{
+			name:            "protobuf request and json response",
+			contentType:     "application/protobuf",
+			accept:          "application/protobuf;q=0.5, application/json",
+			body:            protoBody,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "wildcard accept",
+			contentType:     "application/json; charset=utf-8",
+			accept:          "text/html, */*;q=0.1",
+			body:            []byte(`{"id": "1", "name": "Jane", "age": 28}`),
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "unsupported content type",
+			contentType:     "text/plain",
+			body:            []byte("hello"),
+			wantStatus:      http.StatusUnsupportedMediaType,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "unsupported accept",
+			accept:          "text/html",
+			body:            []byte(`{"id": "1"}`),
+			wantStatus:      http.StatusNotAcceptable,
+			wantContentType: "application/json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/v1/users", bytes.NewReader(tt.body))
+			require.NoError(t, err)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantStatus, res.StatusCode)
+			require.Equal(t, tt.wantContentType, res.Header.Get("Content-Type"))
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			actual := &test.TestUser{}
+			if tt.wantContentType == "application/x-protobuf" {
+				require.NoError(t, proto.Unmarshal(body, actual))
+			} else {
+				require.NoError(t, unmarshalBytes(body, actual, nil))
+			}
+			require.EqualExportedValues(t, user, actual)
+		})
+	}
+}
+
+func TestServer_error(t *testing.T) {
+	addr := newTestServer(t)
+
+	res, err := http.Get("http://" + addr + "/v1/users/missing")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	require.JSONEq(t, `{"code": 5, "message": "user not found", "details": []}`, string(body))
+}
+
+func TestServer_successCode(t *testing.T) {
+	addr := newTestServer(t)
+
+	res, err := http.Post("http://"+addr+"/v1/invites", "application/json", bytes.NewReader([]byte(`{"id": "a b", "name": "Jane"}`)))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Equal(t, "/v1/users/a%20b", res.Header.Get("Location"))
+	require.JSONEq(t, `{"id": "a b", "name": "Jane", "age": 0}`, string(body))
+
+	req, err := http.NewRequest(http.MethodDelete, "http://"+addr+"/v1/users/123", nil)
+	require.NoError(t, err)
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err = io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	require.Empty(t, res.Header.Get("Content-Type"))
+	require.Empty(t, body)
+}
+
+// ruleFiles returns a file with the service ghb.rules.Rules, whose method
+// Call takes and returns a ghb.rules.Reply with the given fields and is
+// served with the given rule.
+func ruleFiles(t *testing.T, rule *api.HttpRule, fields ...*descriptorpb.FieldDescriptorProto) *protoregistry.Files {
+	t.Helper()
+	methodOptions := &descriptorpb.MethodOptions{}
+	proto.SetExtension(methodOptions, api.E_Http, rule)
+	files, err := newFiles([]*descriptorpb.FileDescriptorProto{{
+		Name:       proto.String("rules.proto"),
+		Package:    proto.String("ghb.rules"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"http.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("Reply"),
+			Field: fields,
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Rules"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Call"),
+				InputType:  proto.String(".ghb.rules.Reply"),
+				OutputType: proto.String(".ghb.rules.Reply"),
+				Options:    methodOptions,
+			}},
+		}},
+	}})
+	require.NoError(t, err)
+	return files
+}
+
+// serveErr returns the error of serving s.
+func serveErr(t *testing.T, s *Server) error {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	return s.Serve(lis)
+}
+
+func TestServer_invalidSuccessCode(t *testing.T) {
+	for _, code := range []int32{1000, 99, 302, 404} {
+		s := NewServer()
+		s.RegisterService(&test.TestService_ServiceDesc, testService{})
+		s.RegisterProxyFiles(ruleFiles(t, &api.HttpRule{Path: "/v1/call", Method: api.HttpRule_HttpMethod_POST, SuccessCode: code}), nil)
+		require.ErrorContains(t, serveErr(t, s), fmt.Sprintf("success_code %d of method ghb.rules.Rules.Call is not a 2xx status code", code))
+	}
+
+	s := NewServer()
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	s.RegisterProxyFiles(ruleFiles(t, &api.HttpRule{Path: "/v1/call", Method: api.HttpRule_HttpMethod_POST, SuccessCode: http.StatusAccepted}), nil)
+	serveTest(t, s)
+	require.NoError(t, s.registerProtosOnce())
+}
+
+func Test_expandLocation(t *testing.T) {
+	profile := &test.Profile{Id: "p1", Address: &test.Address{ZipCode: "10115"}}
+	location, err := expandLocation("/v1/profiles/{id}/zips/{address.zipCode}", profile.ProtoReflect())
+	require.NoError(t, err)
+	require.Equal(t, "/v1/profiles/p1/zips/10115", location)
+
+	_, err = expandLocation("/v1/profiles/{unknown}", profile.ProtoReflect())
+	require.Error(t, err)
+	_, err = expandLocation("/v1/profiles/{tags}", profile.ProtoReflect())
+	require.Error(t, err)
+	_, err = expandLocation("/v1/profiles/{id", profile.ProtoReflect())
+	require.Error(t, err)
+}
@@ -0,0 +1,418 @@
+package ghb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// wsServerStream implements grpc.ServerStream on top of a websocket, every
+// text frame carries a single JSON encoded message. The handshake is delayed
+// until the stream is first used so that the header metadata set by the
+// handler can be sent along with it. The connection is read by readLoop from
+// then on, which cancels the context of the stream once the connection is
+// gone, as the hijacked request no longer does.
+type wsServerStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	w      http.ResponseWriter
+	r      *http.Request
+	params map[string]string
+	limits decodeLimits
+
+	mu       sync.Mutex
+	header   metadata.MD
+	trailer  metadata.MD
+	conn     *wsConn
+	err      error
+	messages chan wsMessage
+	eof      bool
+}
+
+// wsMessage is a message read by readLoop, or the error reading it.
+type wsMessage struct {
+	opcode byte
+	body   []byte
+	err    error
+}
+
+func newWSServerStream(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string, limits decodeLimits) *wsServerStream {
+	ctx, cancel := context.WithCancel(ctx)
+	return &wsServerStream{
+		ctx:      ctx,
+		cancel:   cancel,
+		w:        w,
+		r:        r,
+		params:   params,
+		limits:   limits,
+		header:   metadata.MD{},
+		messages: make(chan wsMessage),
+	}
+}
+
+func (s *wsServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *wsServerStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return status.Error(codes.Internal, "transport: the stream is done or WriteHeader was already called")
+	}
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *wsServerStream) SendHeader(md metadata.MD) error {
+	if err := s.SetHeader(md); err != nil {
+		return err
+	}
+	_, err := s.upgrade()
+	return err
+}
+
+// SetTrailer keeps the trailer metadata around for the handler, websockets
+// have no way of sending it after the handshake.
+func (s *wsServerStream) SetTrailer(md metadata.MD) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trailer = metadata.Join(s.trailer, md)
+}
+
+func (s *wsServerStream) SendMsg(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errUnsupportedType(m)
+	}
+	conn, err := s.upgrade()
+	if err != nil {
+		return err
+	}
+	value, err := marshalMessage(msg)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to marshal message: %v", err)
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to marshal message: %v", err)
+	}
+	return conn.writeFrame(wsOpText, body)
+}
+
+func (s *wsServerStream) RecvMsg(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errUnsupportedType(m)
+	}
+	if _, err := s.upgrade(); err != nil {
+		return err
+	}
+	if s.eof {
+		return io.EOF
+	}
+	var message wsMessage
+	select {
+	case message = <-s.messages:
+	case <-s.ctx.Done():
+		return status.FromContextError(s.ctx.Err()).Err()
+	}
+	if message.err == io.EOF {
+		s.eof = true
+		return io.EOF
+	}
+	if message.err != nil {
+		return status.Error(codes.Canceled, message.err.Error())
+	}
+	if message.opcode != wsOpText {
+		return status.Error(codes.InvalidArgument, "only text frames are supported")
+	}
+	if err := unmarshalBytesWith(jsonCodec{}, message.body, msg, s.params, s.limits); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+func (s *wsServerStream) upgrade() (*wsConn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil || s.err != nil {
+		return s.conn, s.err
+	}
+	s.conn, s.err = upgradeWebSocket(s.w, s.r, headerFromMetadata(s.header))
+	if s.err == nil {
+		go s.readLoop(s.conn)
+	}
+	return s.conn, s.err
+}
+
+// readLoop hands the messages of the connection to RecvMsg until the peer
+// closes its side, and keeps reading after that to answer pings and notice
+// the connection going away. The context of the stream is cancelled when
+// the connection fails or is closed.
+func (s *wsServerStream) readLoop(conn *wsConn) {
+	defer s.cancel()
+	closed := false
+	for {
+		opcode, body, err := conn.readMessage()
+		if err != nil && err != io.EOF {
+			select {
+			case s.messages <- wsMessage{err: err}:
+			default:
+			}
+			return
+		}
+		if closed {
+			continue
+		}
+		closed = err == io.EOF
+		select {
+		case s.messages <- wsMessage{opcode: opcode, body: body, err: err}:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// finish closes the websocket with the status returned by the handler, the
+// code is offset into the private range so clients can recover it.
+func (s *wsServerStream) finish(err error) {
+	defer s.cancel()
+	conn, uerr := s.upgrade()
+	if uerr != nil {
+		return
+	}
+	st := status.Convert(err)
+	if st.Code() == codes.OK {
+		conn.close(wsCloseNormal, "")
+		return
+	}
+	conn.close(wsCloseStatusCodeOffset+int(st.Code()), st.Message())
+}
+
+// httpResponseStream is the part shared by the streams that answer with a
+// plain http response instead of upgrading to a websocket.
+type httpResponseStream struct {
+	ctx         context.Context
+	w           http.ResponseWriter
+	contentType string
+
+	mu      sync.Mutex
+	header  metadata.MD
+	trailer metadata.MD
+	sent    bool
+}
+
+func (s *httpResponseStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *httpResponseStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sent {
+		return status.Error(codes.Internal, "transport: the stream is done or WriteHeader was already called")
+	}
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *httpResponseStream) SendHeader(md metadata.MD) error {
+	if err := s.SetHeader(md); err != nil {
+		return err
+	}
+	s.writeHeader()
+	return nil
+}
+
+func (s *httpResponseStream) SetTrailer(md metadata.MD) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trailer = metadata.Join(s.trailer, md)
+}
+
+func (s *httpResponseStream) writeHeader() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sent {
+		return
+	}
+	s.sent = true
+	for key, values := range headerFromMetadata(s.header) {
+		s.w.Header()[key] = values
+	}
+	s.w.Header().Set("Content-Type", s.contentType)
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// finish writes the status returned by the handler, as the response if
+// nothing was sent yet, otherwise as the grpc-status and grpc-message
+// trailers along with the trailer metadata.
+func (s *httpResponseStream) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.sent {
+		if err != nil {
+			writeError(s.w, err)
+		}
+		return
+	}
+	st := status.Convert(err)
+	for key, values := range headerFromMetadata(s.trailer) {
+		s.w.Header()[http.TrailerPrefix+key] = values
+	}
+	s.w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(int(st.Code())))
+	if st.Message() != "" {
+		s.w.Header().Set(http.TrailerPrefix+"Grpc-Message", st.Message())
+	}
+}
+
+// uploadServerStream feeds a multipart/form-data request to a client streaming
+// handler, every file part is received as a message of its own. Plain parts
+// are applied to all the messages of the file parts following them.
+type uploadServerStream struct {
+	httpResponseStream
+	mr     *multipart.Reader
+	dec    multipartDecoder
+	params map[string]string
+	fields map[string][]any
+	codec  messageCodec
+}
+
+func newUploadServerStream(ctx context.Context, w http.ResponseWriter, mr *multipart.Reader, dec multipartDecoder, params map[string]string, contentType string, c messageCodec) *uploadServerStream {
+	return &uploadServerStream{
+		httpResponseStream: httpResponseStream{
+			ctx:         ctx,
+			w:           w,
+			contentType: contentType,
+			header:      metadata.MD{},
+		},
+		mr:     mr,
+		dec:    dec,
+		params: params,
+		fields: map[string][]any{},
+		codec:  c,
+	}
+}
+
+func (s *uploadServerStream) SendMsg(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errUnsupportedType(m)
+	}
+	body, err := s.codec.marshal(msg)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to marshal message: %v", err)
+	}
+	if err := setResponseHeaders(s.w.Header(), msg.ProtoReflect()); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	s.writeHeader()
+	_, err = s.w.Write(body)
+	return err
+}
+
+func (s *uploadServerStream) RecvMsg(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errUnsupportedType(m)
+	}
+	for {
+		part, err := s.mr.NextPart()
+		if err == io.EOF {
+			return io.EOF
+		}
+		if errors.Is(err, errBodyTooLarge) {
+			return err
+		}
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		name, value, err := s.dec.readPart(part)
+		if errors.Is(err, errBodyTooLarge) {
+			return err
+		}
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if _, ok := value.(string); ok {
+			s.fields[name] = []any{value}
+			continue
+		}
+		form := make(map[string][]any, len(s.fields)+1)
+		for k, v := range s.fields {
+			form[k] = v
+		}
+		form[name] = []any{value}
+		if err := unmarshalForm(msg, form, s.params, s.dec.limits); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil
+	}
+}
+
+// httpBodyServerStream serves a server streaming method returning
+// google.api.HttpBody as a plain http response, the data of every message is
+// written and flushed as it is sent. The Content-Type is taken from the first
+// message.
+type httpBodyServerStream struct {
+	httpResponseStream
+	params   map[string]string
+	received bool
+}
+
+func newHttpBodyServerStream(ctx context.Context, w http.ResponseWriter, params map[string]string) *httpBodyServerStream {
+	return &httpBodyServerStream{
+		httpResponseStream: httpResponseStream{
+			ctx:         ctx,
+			w:           w,
+			contentType: contentTypeOctetStream,
+			header:      metadata.MD{},
+		},
+		params: params,
+	}
+}
+
+func (s *httpBodyServerStream) SendMsg(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok || !isHttpBody(msg.ProtoReflect().Descriptor()) {
+		return errUnsupportedType(m)
+	}
+	contentType, data := getHttpBody(msg.ProtoReflect())
+	s.mu.Lock()
+	if !s.sent && contentType != "" {
+		s.contentType = contentType
+	}
+	s.mu.Unlock()
+	s.writeHeader()
+	if _, err := s.w.Write(data); err != nil {
+		return err
+	}
+	return http.NewResponseController(s.w).Flush()
+}
+
+// RecvMsg decodes the request from the path params, there is no body.
+func (s *httpBodyServerStream) RecvMsg(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errUnsupportedType(m)
+	}
+	if s.received {
+		return io.EOF
+	}
+	s.received = true
+	if err := unmarshalBytes(nil, msg, s.params); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
@@ -1,9 +1,12 @@
 package ghb
 
 import (
-	"encoding/json"
+	"encoding/base64"
 	"fmt"
+	"math"
 	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/malayanand/ghb/api"
@@ -16,6 +19,10 @@ type Unmarshaler interface {
 }
 
 func unmarshalBytes(bytes []byte, msg proto.Message, params map[string]string) error {
+	return unmarshalBytesWith(jsonCodec{}, bytes, msg, params, decodeLimits{})
+}
+
+func unmarshalBytesWith(c Codec, bytes []byte, msg proto.Message, params map[string]string, limits decodeLimits) error {
 	value := map[string]any{}
 	for k, v := range params {
 		if existing, ok := value[k]; ok {
@@ -24,11 +31,14 @@ func unmarshalBytes(bytes []byte, msg proto.Message, params map[string]string) e
 		value[k] = v
 	}
 	if bytes != nil {
-		err := json.Unmarshal(bytes, &value)
+		err := c.Unmarshal(bytes, &value)
 		if err != nil {
 			return fmt.Errorf("failed to unmarshal request body: %v", err)
 		}
 	}
+	if err := limits.checkValue(value); err != nil {
+		return err
+	}
 	return unmarshalMessage(msg, value)
 }
 
@@ -61,6 +71,10 @@ func unmarshalMessage(msg proto.Message, value any) error {
 		if fd == nil {
 			return fmt.Errorf("field descriptor for %s not found", protoKey)
 		}
+		// null leaves the field unset.
+		if v == nil {
+			continue
+		}
 		if fd.IsMap() {
 			if err := unmarshalMap(fd, msg, v); err != nil {
 				return err
@@ -99,23 +113,23 @@ func unmarshalMap(fd protoreflect.FieldDescriptor, msg proto.Message, value any)
 	}
 	mp := msg.ProtoReflect().Mutable(fd).Map()
 	for k, v := range mapValue {
-		val := mp.NewValue()
-		if fd.IsList() {
-			if err := unmarshalList(fd, msg, v); err != nil {
-				return err
-			}
-		} else if fd.Kind() == protoreflect.MessageKind {
+		key, err := scalarValue(fd.MapKey(), k)
+		if err != nil {
+			return err
+		}
+		if fd.MapValue().Kind() == protoreflect.MessageKind {
+			val := mp.NewValue()
 			if err := unmarshalMessage(val.Message().Interface(), v); err != nil {
 				return err
 			}
-		} else {
-			if err := unmarshalField(fd, msg, v); err != nil {
-				return err
-			}
+			mp.Set(key.MapKey(), val)
+			continue
 		}
-		// TODO: handle support for all types of key values
-		keyType := protoreflect.ValueOfString(k).MapKey()
-		mp.Set(keyType, val)
+		val, err := scalarValue(fd.MapValue(), v)
+		if err != nil {
+			return err
+		}
+		mp.Set(key.MapKey(), val)
 	}
 	return nil
 }
@@ -127,16 +141,19 @@ func unmarshalList(fd protoreflect.FieldDescriptor, msg proto.Message, value any
 	}
 	list := msg.ProtoReflect().Mutable(fd).List()
 	for _, v := range listValue {
-		val := list.AppendMutable()
 		if fd.Kind() == protoreflect.MessageKind {
+			val := list.NewElement()
 			if err := unmarshalMessage(val.Message().Interface(), v); err != nil {
 				return err
 			}
-		} else {
-			if err := unmarshalField(fd, msg, v); err != nil {
-				return err
-			}
+			list.Append(val)
+			continue
 		}
+		val, err := scalarValue(fd, v)
+		if err != nil {
+			return err
+		}
+		list.Append(val)
 	}
 	return nil
 }
@@ -153,40 +170,141 @@ func unmarshalField(fd protoreflect.FieldDescriptor, msg proto.Message, value an
 func scalarValue(fd protoreflect.FieldDescriptor, v any) (protoreflect.Value, error) {
 	switch fd.Kind() {
 	case protoreflect.BoolKind:
-		return protoreflect.ValueOfBool(v.(bool)), nil
-	case protoreflect.Int32Kind:
-		return protoreflect.ValueOfInt32(int32(v.(float64))), nil
-	case protoreflect.Sint32Kind:
-		return protoreflect.ValueOfInt32(int32(v.(float64))), nil
-	case protoreflect.Uint32Kind:
-		return protoreflect.ValueOfUint32(uint32(v.(float64))), nil
-	case protoreflect.Int64Kind:
-		return protoreflect.ValueOfInt64(int64(v.(float64))), nil
-	case protoreflect.Sint64Kind:
-		return protoreflect.ValueOfInt64(int64(v.(float64))), nil
-	case protoreflect.Uint64Kind:
-		return protoreflect.ValueOfUint64(uint64(v.(float64))), nil
-	case protoreflect.Sfixed32Kind:
-		return protoreflect.ValueOfInt32(int32(v.(float64))), nil
-	case protoreflect.Fixed32Kind:
-		return protoreflect.ValueOfUint32(uint32(v.(float64))), nil
-	case protoreflect.Sfixed64Kind:
-		return protoreflect.ValueOfInt64(int64(v.(float64))), nil
-	case protoreflect.Fixed64Kind:
-		return protoreflect.ValueOfUint64(uint64(v.(float64))), nil
+		b, err := boolValue(v)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := intValue(v, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := intValue(v, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := uintValue(v, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := uintValue(v, 64)
+		return protoreflect.ValueOfUint64(n), err
 	case protoreflect.FloatKind:
-		return protoreflect.ValueOfFloat32(float32(v.(float64))), nil
+		f, err := floatValue(v, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
 	case protoreflect.DoubleKind:
-		return protoreflect.ValueOfFloat64(v.(float64)), nil
+		f, err := floatValue(v, 64)
+		return protoreflect.ValueOfFloat64(f), err
 	case protoreflect.StringKind:
-		return protoreflect.ValueOfString(v.(string)), nil
+		s, ok := v.(string)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("expected string for field %s, got %T", fd.Name(), v)
+		}
+		return protoreflect.ValueOfString(s), nil
 	case protoreflect.BytesKind:
-		return protoreflect.ValueOfBytes([]byte(v.(string))), nil
+		switch b := v.(type) {
+		case string:
+			data, err := decodeBytes(b)
+			if err != nil {
+				return protoreflect.Value{}, fmt.Errorf("invalid base64 for field %s: %v", fd.Name(), err)
+			}
+			return protoreflect.ValueOfBytes(data), nil
+		case []byte:
+			return protoreflect.ValueOfBytes(b), nil
+		}
+		return protoreflect.Value{}, fmt.Errorf("expected bytes for field %s, got %T", fd.Name(), v)
 	default:
 		return protoreflect.Value{}, fmt.Errorf("unsupported type: %T", v)
 	}
 }
 
+// boolValue, intValue, uintValue and floatValue accept any numeric go type,
+// so that codecs other than JSON can be used, as well as strings which is
+// what path parameters are.
+func boolValue(v any) (bool, error) {
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case string:
+		return strconv.ParseBool(b)
+	}
+	return false, fmt.Errorf("expected bool, got %T", v)
+}
+
+func intValue(v any, bitSize int) (int64, error) {
+	rv := reflect.ValueOf(v)
+	var n int64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("integer %v out of range", rv.Uint())
+		}
+		n = int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) {
+			return 0, fmt.Errorf("expected integer, got %v", f)
+		}
+		// -2^63 is exact as a float, 2^63 is already out of range.
+		limit := math.Ldexp(1, bitSize-1)
+		if f < -limit || f >= limit {
+			return 0, fmt.Errorf("integer %v out of range", f)
+		}
+		n = int64(f)
+	case reflect.String:
+		return strconv.ParseInt(rv.String(), 10, bitSize)
+	default:
+		return 0, fmt.Errorf("expected integer, got %T", v)
+	}
+	if min, max := int64(-1)<<(bitSize-1), int64(1)<<(bitSize-1)-1; n < min || n > max {
+		return 0, fmt.Errorf("integer %v out of range", n)
+	}
+	return n, nil
+}
+
+func uintValue(v any, bitSize int) (uint64, error) {
+	rv := reflect.ValueOf(v)
+	var n uint64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() < 0 {
+			return 0, fmt.Errorf("expected unsigned integer, got %v", rv.Int())
+		}
+		n = uint64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f < 0 || f != math.Trunc(f) {
+			return 0, fmt.Errorf("expected unsigned integer, got %v", f)
+		}
+		if f >= math.Ldexp(1, bitSize) {
+			return 0, fmt.Errorf("integer %v out of range", f)
+		}
+		n = uint64(f)
+	case reflect.String:
+		return strconv.ParseUint(rv.String(), 10, bitSize)
+	default:
+		return 0, fmt.Errorf("expected unsigned integer, got %T", v)
+	}
+	if max := uint64(1)<<(bitSize-1)<<1 - 1; n > max {
+		return 0, fmt.Errorf("integer %v out of range", n)
+	}
+	return n, nil
+}
+
+func floatValue(v any, bitSize int) (float64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return strconv.ParseFloat(rv.String(), bitSize)
+	}
+	return 0, fmt.Errorf("expected number, got %T", v)
+}
+
 func extractURLParams(pattern, path string) (map[string]string, error) {
 	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
 	pathParts := strings.Split(strings.Trim(path, "/"), "/")
@@ -214,25 +332,42 @@ func extractURLParams(pattern, path string) (map[string]string, error) {
 }
 
 func jsonToProtoKeys(msg proto.Message) (map[string]string, error) {
-	fields := msg.ProtoReflect().Descriptor().Fields()
+	return descriptorKeys(msg.ProtoReflect().Descriptor())
+}
+
+func descriptorKeys(md protoreflect.MessageDescriptor) (map[string]string, error) {
+	fields := md.Fields()
 	keyMap := make(map[string]string)
 	for i := 0; i < fields.Len(); i++ {
 		fd := fields.Get(i)
-		rule := proto.GetExtension(fd.Options(), api.E_Field).(*api.FieldRule)
-		// If no rule is specified, then the field name is the key.
-		if rule == nil {
-			if _, ok := keyMap[string(fd.Name())]; ok {
-				return nil, fmt.Errorf("same field %s is specified twice", fd.Name())
-			}
-			keyMap[string(fd.Name())] = string(fd.Name())
-			continue
-		}
-		if rule.GetJsonName() != "" {
-			if _, ok := keyMap[rule.GetJsonName()]; ok {
-				return nil, fmt.Errorf("same field %s is specified twice", rule.GetJsonName())
-			}
-			keyMap[rule.GetJsonName()] = string(fd.Name())
+		key := jsonKey(fd)
+		if _, ok := keyMap[key]; ok {
+			return nil, fmt.Errorf("same field %s is specified twice", key)
 		}
+		keyMap[key] = string(fd.Name())
 	}
 	return keyMap, nil
 }
+
+// jsonKey is the key of a field in the encoded message, the json_name of the
+// field rule if one is specified, otherwise the field name.
+func jsonKey(fd protoreflect.FieldDescriptor) string {
+	rule := proto.GetExtension(fd.Options(), api.E_Field).(*api.FieldRule)
+	if rule.GetJsonName() != "" {
+		return rule.GetJsonName()
+	}
+	return string(fd.Name())
+}
+
+// decodeBytes decodes the base64 text of a bytes field, in the standard or the
+// URL safe alphabet with or without padding, just like protojson accepts it.
+func decodeBytes(s string) ([]byte, error) {
+	enc := base64.StdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.URLEncoding
+	}
+	if len(s)%4 != 0 {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+	return enc.DecodeString(s)
+}
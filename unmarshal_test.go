@@ -1,6 +1,7 @@
 package ghb
 
 import (
+	"math"
 	"testing"
 
 	"github.com/malayanand/ghb/test"
@@ -129,3 +130,60 @@ func Test_unmarshalBytes(t *testing.T) {
 		})
 	}
 }
+
+func Test_intValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		bitSize int
+		want    int64
+		wantErr bool
+	}{
+		{name: "int32", value: float64(math.MaxInt32), bitSize: 32, want: math.MaxInt32},
+		{name: "int32 overflow", value: float64(math.MaxInt32 + 1), bitSize: 32, wantErr: true},
+		{name: "int32 underflow", value: int64(math.MinInt32 - 1), bitSize: 32, wantErr: true},
+		{name: "int32 string overflow", value: "2147483648", bitSize: 32, wantErr: true},
+		{name: "int64 from uint64 overflow", value: uint64(math.MaxUint64), bitSize: 64, wantErr: true},
+		{name: "int64 min from float", value: float64(math.MinInt64), bitSize: 64, want: math.MinInt64},
+		{name: "int64 float overflow", value: math.Ldexp(1, 63), bitSize: 64, wantErr: true},
+		{name: "fraction", value: 1.5, bitSize: 64, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := intValue(tt.value, tt.bitSize)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, n)
+		})
+	}
+}
+
+func Test_uintValue(t *testing.T) {
+	n, err := uintValue(float64(math.MaxUint32), 32)
+	require.NoError(t, err)
+	require.Equal(t, uint64(math.MaxUint32), n)
+
+	_, err = uintValue(float64(math.MaxUint32+1), 32)
+	require.Error(t, err)
+	_, err = uintValue(uint64(math.MaxUint32+1), 32)
+	require.Error(t, err)
+	_, err = uintValue(math.Ldexp(1, 64), 64)
+	require.Error(t, err)
+
+	n, err = uintValue(uint64(math.MaxUint64), 64)
+	require.NoError(t, err)
+	require.Equal(t, uint64(math.MaxUint64), n)
+}
+
+func Test_decodeBytes(t *testing.T) {
+	for _, s := range []string{"/wBwbmc=", "/wBwbmc", "_wBwbmc=", "_wBwbmc"} {
+		data, err := decodeBytes(s)
+		require.NoError(t, err, s)
+		require.Equal(t, []byte{0xff, 0x00, 'p', 'n', 'g'}, data, s)
+	}
+	_, err := decodeBytes("not base64!")
+	require.Error(t, err)
+}
@@ -0,0 +1,303 @@
+package ghb
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xa
+
+	wsCloseNormal           = 1000
+	wsCloseProtocolError    = 1002
+	wsCloseMessageTooBig    = 1009
+	wsCloseStatusCodeOffset = 4000
+
+	// same as the default maximum receive message size of a grpc server.
+	maxWebSocketMessageSize = 4 << 20
+	maxWebSocketCloseReason = 123
+
+	webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+var errWebSocketClosed = errors.New("websocket closed")
+
+// wsConn is a server side websocket connection as described in RFC 6455.
+type wsConn struct {
+	conn net.Conn
+	brw  *bufio.ReadWriter
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func checkWebSocketHandshake(r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return fmt.Errorf("websocket handshake requires GET, got %s", r.Method)
+	}
+	if !isWebSocketUpgrade(r) {
+		return fmt.Errorf("websocket upgrade headers are missing")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return fmt.Errorf("unsupported websocket version %q", r.Header.Get("Sec-WebSocket-Version"))
+	}
+	if r.Header.Get("Sec-WebSocket-Key") == "" {
+		return fmt.Errorf("missing Sec-WebSocket-Key header")
+	}
+	return nil
+}
+
+// checkWebSocketOrigin rejects handshakes from pages of another origin, which
+// browsers make with the cookies of the user regardless of CORS, unless the
+// CORS policy allows the origin. Requests without an Origin are not made by
+// browsers and are accepted.
+func (s *Server) checkWebSocketOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+	if s.cors != nil {
+		if _, ok := s.cors.allowOrigin(origin); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("websocket origin %q is not allowed", origin)
+}
+
+// upgradeWebSocket completes the websocket handshake, the given header is
+// sent along with the 101 Switching Protocols response.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, header http.Header) (*wsConn, error) {
+	if err := checkWebSocketHandshake(r); err != nil {
+		return nil, err
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack connection: %v", err)
+	}
+
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	brw.WriteString("Upgrade: websocket\r\n")
+	brw.WriteString("Connection: Upgrade\r\n")
+	brw.WriteString("Sec-WebSocket-Accept: " + webSocketAccept(key) + "\r\n")
+	if err := header.Write(brw); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	brw.WriteString("\r\n")
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, brw: brw}, nil
+}
+
+func webSocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// readMessage returns the next data message, reassembling fragmented frames
+// and answering control frames on the way. A close frame from the peer is
+// reported as io.EOF.
+func (c *wsConn) readMessage() (byte, []byte, error) {
+	var opcode byte
+	var message []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			return 0, nil, io.EOF
+		case wsOpContinuation:
+			if opcode == 0 {
+				return 0, nil, c.fail(wsCloseProtocolError, "unexpected continuation frame")
+			}
+		case wsOpText, wsOpBinary:
+			if opcode != 0 {
+				return 0, nil, c.fail(wsCloseProtocolError, "expected continuation frame")
+			}
+			opcode = op
+		default:
+			return 0, nil, c.fail(wsCloseProtocolError, fmt.Sprintf("unknown opcode %d", op))
+		}
+		if len(message)+len(payload) > maxWebSocketMessageSize {
+			return 0, nil, c.fail(wsCloseMessageTooBig, "message too big")
+		}
+		message = append(message, payload...)
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.brw, head[:]); err != nil {
+		// io.EOF is reserved for the close frame of the peer.
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return false, 0, nil, err
+	}
+	fin := head[0]&0x80 != 0
+	opcode := head[0] & 0x0f
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(wsCloseProtocolError, "reserved bits are set")
+	}
+	// clients must mask every frame they send.
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(wsCloseProtocolError, "frame is not masked")
+	}
+
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.brw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.brw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if opcode >= wsOpClose && (length > 125 || !fin) {
+		return false, 0, nil, c.fail(wsCloseProtocolError, "invalid control frame")
+	}
+	if length > maxWebSocketMessageSize {
+		return false, 0, nil, c.fail(wsCloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.brw, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.brw, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return errWebSocketClosed
+	}
+
+	var head [10]byte
+	head[0] = 0x80 | opcode
+	n := 2
+	switch length := len(payload); {
+	case length <= 125:
+		head[1] = byte(length)
+	case length <= 0xffff:
+		head[1] = 126
+		binary.BigEndian.PutUint16(head[2:], uint16(length))
+		n += 2
+	default:
+		head[1] = 127
+		binary.BigEndian.PutUint64(head[2:], uint64(length))
+		n += 8
+	}
+	if _, err := c.brw.Write(head[:n]); err != nil {
+		return err
+	}
+	if _, err := c.brw.Write(payload); err != nil {
+		return err
+	}
+	return c.brw.Flush()
+}
+
+// close sends a close frame with the given code and reason and closes the
+// underlying connection.
+func (c *wsConn) close(code int, reason string) error {
+	reason = closeReason(reason)
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	err := c.writeFrame(wsOpClose, payload)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return err
+	}
+	c.closed = true
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// closeReason makes reason fit into a close frame, which requires valid UTF-8,
+// by cutting it at the last rune boundary within the limit.
+func closeReason(reason string) string {
+	reason = strings.ToValidUTF8(reason, "")
+	if len(reason) <= maxWebSocketCloseReason {
+		return reason
+	}
+	n := maxWebSocketCloseReason
+	for n > 0 && !utf8.RuneStart(reason[n]) {
+		n--
+	}
+	return reason[:n]
+}
+
+func (c *wsConn) fail(code int, reason string) error {
+	c.close(code, reason)
+	return fmt.Errorf("websocket: %s", reason)
+}
@@ -0,0 +1,197 @@
+package ghb
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/malayanand/ghb/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type testWSClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialTestWS(t *testing.T, addr, path string, header http.Header) (*testWSClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	key := make([]byte, 16)
+	rand.Read(key)
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+path, nil)
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+	require.NoError(t, req.Write(conn))
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	require.Equal(t, webSocketAccept(req.Header.Get("Sec-WebSocket-Key")), res.Header.Get("Sec-WebSocket-Accept"))
+	return &testWSClient{conn: conn, br: br}, res
+}
+
+func (c *testWSClient) write(t *testing.T, opcode byte, payload []byte) {
+	t.Helper()
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, 0x80|byte(len(payload)))
+	default:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := c.conn.Write(frame)
+	require.NoError(t, err)
+}
+
+func (c *testWSClient) read(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	_, err := io.ReadFull(c.br, head[:])
+	require.NoError(t, err)
+	length := int(head[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		_, err := io.ReadFull(c.br, ext[:])
+		require.NoError(t, err)
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.br, payload)
+	require.NoError(t, err)
+	return head[0] & 0x0f, payload
+}
+
+func (c *testWSClient) readClose(t *testing.T) (int, string) {
+	t.Helper()
+	opcode, payload := c.read(t)
+	require.Equal(t, byte(wsOpClose), opcode)
+	require.GreaterOrEqual(t, len(payload), 2)
+	return int(binary.BigEndian.Uint16(payload)), string(payload[2:])
+}
+
+func TestServer_webSocketStream(t *testing.T) {
+	addr := newTestServer(t)
+	client, res := dialTestWS(t, addr, "/v1/rooms/general/chat", http.Header{"X-User": {"jane"}})
+	require.Equal(t, "hello jane", res.Header.Get("X-Greeting"))
+
+	client.write(t, wsOpText, []byte(`{"text": "hi"}`))
+	opcode, payload := client.read(t)
+	require.Equal(t, byte(wsOpText), opcode)
+	require.JSONEq(t, `{"room": "general", "text": "echo: hi"}`, string(payload))
+
+	client.write(t, wsOpPing, []byte("ping"))
+	opcode, payload = client.read(t)
+	require.Equal(t, byte(wsOpPong), opcode)
+	require.Equal(t, "ping", string(payload))
+
+	client.write(t, wsOpClose, []byte{0x03, 0xe8})
+	code, _ := client.readClose(t)
+	require.Equal(t, wsCloseNormal, code)
+}
+
+func TestServer_webSocketStreamStatus(t *testing.T) {
+	addr := newTestServer(t)
+	client, _ := dialTestWS(t, addr, "/v1/rooms/general/chat", nil)
+
+	client.write(t, wsOpText, []byte(`{"text": "bye"}`))
+	code, reason := client.readClose(t)
+	require.Equal(t, wsCloseStatusCodeOffset+10, code) // codes.Aborted
+	require.Equal(t, "conversation ended", reason)
+}
+
+func TestServer_webSocketUpgradeRequired(t *testing.T) {
+	addr := newTestServer(t)
+	res, err := http.Get("http://" + addr + "/v1/rooms/general/chat")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusUpgradeRequired, res.StatusCode)
+}
+
+func TestServer_webSocketOrigin(t *testing.T) {
+	handshake := func(t *testing.T, addr, origin string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/v1/rooms/general/chat", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		res, err := http.DefaultTransport.RoundTrip(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	addr := newTestServer(t)
+	require.Equal(t, http.StatusForbidden, handshake(t, addr, "https://evil.example").StatusCode)
+	require.Equal(t, http.StatusSwitchingProtocols, handshake(t, addr, "http://"+addr).StatusCode)
+
+	s := NewServer(WithCORS(CORSPolicy{AllowedOrigins: []string{"https://*.example.com"}}))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr = serveTest(t, s)
+	require.Equal(t, http.StatusSwitchingProtocols, handshake(t, addr, "https://app.example.com").StatusCode)
+	require.Equal(t, http.StatusForbidden, handshake(t, addr, "https://evil.example").StatusCode)
+}
+
+func TestServer_webSocketDisconnect(t *testing.T) {
+	done := make(chan error, 1)
+	s := NewServer(WithStreamInterceptors(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		done <- ss.Context().Err()
+		return err
+	}))
+	s.RegisterService(&test.TestService_ServiceDesc, testService{})
+	addr := serveTest(t, s)
+
+	client, _ := dialTestWS(t, addr, "/v1/rooms/general/chat", nil)
+	client.write(t, wsOpText, []byte(`{"text": "hi"}`))
+	client.read(t)
+	client.conn.Close()
+
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler kept running after the client disconnected")
+	}
+}
+
+func Test_closeReason(t *testing.T) {
+	require.Equal(t, "conversation ended", closeReason("conversation ended"))
+
+	reason := closeReason(strings.Repeat("a", maxWebSocketCloseReason-1) + "é")
+	require.Equal(t, strings.Repeat("a", maxWebSocketCloseReason-1), reason)
+
+	reason = closeReason(strings.Repeat("日本", 100))
+	require.LessOrEqual(t, len(reason), maxWebSocketCloseReason)
+	require.True(t, utf8.ValidString(reason))
+	require.Equal(t, "nope", closeReason("no\xffpe"))
+}